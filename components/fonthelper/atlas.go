@@ -0,0 +1,190 @@
+package fonthelper
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"sync"
+
+	"github.com/oakmound/oak/v4/render"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultAtlasChars are the printable ASCII characters.
+const DefaultAtlasChars = " !\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+
+// atlasWidth is the width glyphs are packed into in an atlas' sheet.
+const atlasWidth = 512
+
+// atlasSubPixels is how many horizontal positions within a pixel each glyph is
+// rendered at, matching the default of the truetype faces fonts use.
+const atlasSubPixels = 4
+
+// An Atlas holds glyphs of a font pre-rendered into one image, so that text
+// drawn from it is copied from that image rather than rasterized each frame.
+// This suits text that changes often, like scores and timers.
+type Atlas struct {
+	// sheet holds the coverage of each glyph, drawn in src
+	sheet  *image.Alpha
+	src    image.Image
+	glyphs map[rune]atlasGlyph
+	height float64
+
+	// faceLock guards face, which is kept for kerning
+	faceLock sync.Mutex
+	face     font.Face
+}
+
+type atlasGlyph struct {
+	// each glyph is rendered once per sub-pixel position
+	cells   [atlasSubPixels]atlasCell
+	advance fixed.Int26_6
+}
+
+type atlasCell struct {
+	// area is the glyph's area in the sheet, and offset is where that area
+	// is drawn relative to the pixel containing the glyph's origin.
+	area   image.Rectangle
+	offset image.Point
+}
+
+// NewAtlas renders each of chars in f, at f's size and color, into an atlas.
+// Characters f has no glyph for are left out. Text drawn from the atlas skips
+// characters that were not baked into it.
+func NewAtlas(f *render.Font, chars string) (*Atlas, error) {
+	face := f.Copy().Face
+	a := &Atlas{
+		src:    f.Src,
+		glyphs: make(map[rune]atlasGlyph),
+		height: f.Height(),
+		face:   face,
+	}
+
+	type baked struct {
+		area image.Rectangle
+		mask image.Image
+	}
+	var (
+		all          []baked
+		x, y, rowMax int
+	)
+	for _, r := range chars {
+		if _, ok := a.glyphs[r]; ok {
+			continue
+		}
+		var g atlasGlyph
+		ok := true
+		for sub := 0; sub < atlasSubPixels && ok; sub++ {
+			dot := fixed.Point26_6{X: fixed.Int26_6(sub * 64 / atlasSubPixels)}
+			var (
+				dr    image.Rectangle
+				mask  image.Image
+				maskp image.Point
+			)
+			dr, mask, maskp, g.advance, ok = face.Glyph(dot, r)
+			if !ok {
+				break
+			}
+			// copy the mask, as faces reuse their mask between calls
+			m := image.NewAlpha(image.Rect(0, 0, dr.Dx(), dr.Dy()))
+			draw.Draw(m, m.Bounds(), mask, maskp, draw.Src)
+
+			// pack cells left to right in rows, with a pixel between cells
+			// so that neighbors cannot bleed into each other
+			if x+dr.Dx() > atlasWidth {
+				x, y = 0, y+rowMax+1
+				rowMax = 0
+			}
+			area := image.Rect(x, y, x+dr.Dx(), y+dr.Dy())
+			g.cells[sub] = atlasCell{area: area, offset: dr.Min}
+			all = append(all, baked{area: area, mask: m})
+			x += dr.Dx() + 1
+			if dr.Dy() > rowMax {
+				rowMax = dr.Dy()
+			}
+		}
+		if ok {
+			a.glyphs[r] = g
+		}
+	}
+	if len(a.glyphs) == 0 {
+		return nil, fmt.Errorf("font has no glyphs for %q", chars)
+	}
+
+	a.sheet = image.NewAlpha(image.Rect(0, 0, atlasWidth, y+rowMax))
+	for _, b := range all {
+		draw.Draw(a.sheet, b.area, b.mask, image.Point{}, draw.Src)
+	}
+	return a, nil
+}
+
+// MeasureString returns the width s would be drawn at, including kerning.
+func (a *Atlas) MeasureString(s string) fixed.Int26_6 {
+	var width fixed.Int26_6
+	prev := rune(-1)
+	for _, r := range s {
+		g, ok := a.glyphs[r]
+		if !ok {
+			continue
+		}
+		if prev >= 0 {
+			width += a.kern(prev, r)
+		}
+		width += g.advance
+		prev = r
+	}
+	return width
+}
+
+// Height returns the size of the font the atlas was made from.
+func (a *Atlas) Height() float64 {
+	return a.height
+}
+
+func (a *Atlas) kern(prev, r rune) fixed.Int26_6 {
+	a.faceLock.Lock()
+	defer a.faceLock.Unlock()
+	return a.face.Kern(prev, r)
+}
+
+// drawString draws s with the top of its line at x, y. Like render.Text, the
+// baseline is the atlas' Height below the top of the line.
+func (a *Atlas) drawString(buff draw.Image, s string, x, y int) {
+	y += int(a.height)
+	dot := fixed.I(x)
+	prev := rune(-1)
+	for _, r := range s {
+		g, ok := a.glyphs[r]
+		if !ok {
+			continue
+		}
+		if prev >= 0 {
+			dot += a.kern(prev, r)
+		}
+		prev = r
+		// round to the nearest sub-pixel position, as truetype faces do
+		sub := (dot + 32/atlasSubPixels) & -(64 / atlasSubPixels)
+		cell := g.cells[int(sub&63)*atlasSubPixels/64]
+		pos := image.Pt(sub.Floor(), y).Add(cell.offset)
+		draw.DrawMask(buff, cell.area.Sub(cell.area.Min).Add(pos), a.src, image.Point{}, a.sheet, cell.area.Min, draw.Over)
+		dot += g.advance
+	}
+}
+
+// NewText creates a renderable text with the given string body. Like a
+// render.Text, its baseline is the atlas' Height below y.
+func (a *Atlas) NewText(str string, x, y float64) *Text {
+	return a.NewStringerText(textString(str), x, y)
+}
+
+// NewStrPtrText creates a renderable text which draws the string behind str.
+func (a *Atlas) NewStrPtrText(str *string, x, y float64) *Text {
+	return a.NewStringerText(textStringPtr{str}, x, y)
+}
+
+// NewStringerText creates a renderable text which draws the string provided
+// by str each frame.
+func (a *Atlas) NewStringerText(str fmt.Stringer, x, y float64) *Text {
+	return newText(a, str, x, y)
+}
@@ -0,0 +1,445 @@
+package fonthelper
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/oakmound/oak/v4/render"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// A Direction is the direction text is read in.
+type Direction int
+
+// Directions text can be read in.
+const (
+	LeftToRight Direction = iota
+	RightToLeft
+)
+
+// ParagraphDirection returns the direction of s as the Unicode bidirectional
+// algorithm determines it: the direction of its first strongly directional
+// character, or LeftToRight if it has none.
+func ParagraphDirection(s string) Direction {
+	for _, r := range s {
+		p, _ := bidi.LookupRune(r)
+		switch p.Class() {
+		case bidi.L:
+			return LeftToRight
+		case bidi.R, bidi.AL:
+			return RightToLeft
+		}
+	}
+	return LeftToRight
+}
+
+// Visual returns s reordered into the order its characters are displayed in,
+// from left to right, following the Unicode bidirectional algorithm. Right to
+// left runs are reversed, with their brackets mirrored, and runs are ordered
+// by the direction of the paragraph they are in. Each line of s is reordered
+// on its own.
+//
+// Fonts draw strings from left to right, so mixed direction and right to left
+// text should be reordered with Visual before it is drawn or measured. Visual
+// does not shape text: scripts whose letters join, like Arabic, need fonts and
+// strings with those forms already applied.
+func Visual(s string) string {
+	glyphs, _ := visualGlyphs(s)
+	buf := make([]byte, 0, len(s))
+	for _, g := range glyphs {
+		buf = utf8.AppendRune(buf, g.r)
+	}
+	return string(buf)
+}
+
+// MeasureVisual returns the width s is drawn at by m once it is reordered with
+// Visual. Kerning depends on which glyphs end up next to each other, so this
+// can differ from measuring s as stored.
+func MeasureVisual(m Measurer, s string) fixed.Int26_6 {
+	return m.MeasureString(Visual(s))
+}
+
+// VisualStringer wraps str so that the string it provides is reordered with
+// Visual. Texts which draw a fmt.Stringer, such as those from NewStringerText,
+// draw mixed direction strings in display order through it.
+func VisualStringer(str fmt.Stringer) fmt.Stringer {
+	return &visualStringer{str: str}
+}
+
+type visualStringer struct {
+	str fmt.Stringer
+
+	// the last string reordered is kept, as texts ask for their string
+	// every frame
+	lock           sync.Mutex
+	logical, shown string
+}
+
+func (vs *visualStringer) String() string {
+	s := vs.str.String()
+	vs.lock.Lock()
+	defer vs.lock.Unlock()
+	if s != vs.logical {
+		vs.logical = s
+		vs.shown = Visual(s)
+	}
+	return vs.shown
+}
+
+// NewVisualText creates a text drawing s from f in display order.
+func NewVisualText(f *render.Font, s string, x, y float64) *render.Text {
+	return f.NewStringerText(VisualStringer(textString(s)), x, y)
+}
+
+// An Align positions a line of text relative to the direction it is read in.
+type Align int
+
+// Alignments of text.
+const (
+	// AlignStart aligns left to right text to the left, and right to left
+	// text to the right.
+	AlignStart Align = iota
+	// AlignEnd aligns left to right text to the right, and right to left text
+	// to the left.
+	AlignEnd
+	AlignCenter
+)
+
+// AlignOffset returns how far from the left of a box width wide s should be
+// drawn for it to be aligned by a, measuring s in display order with m.
+func AlignOffset(m Measurer, s string, width float64, a Align) float64 {
+	space := width - fixedToFloat(MeasureVisual(m, s))
+	if a == AlignCenter {
+		return space / 2
+	}
+	if (a == AlignStart) == (ParagraphDirection(s) == RightToLeft) {
+		return space
+	}
+	return 0
+}
+
+// A Caret maps the position of a text cursor in a line of mixed direction text
+// between the order the text is stored and edited in and the order it is
+// displayed in.
+//
+// Indexes are byte offsets into the string, as used to slice it. Slots are
+// positions between displayed characters, counted from the left, where slot 0
+// is left of the first character displayed and slot Len is right of the last.
+// Where runs of different directions meet, one index is displayed in two
+// places, so components moving a cursor with the arrow keys should keep the
+// slot it is in, and use Index to edit the text there.
+type Caret struct {
+	str    string
+	shown  string
+	glyphs []visualGlyph
+	// offsets holds the byte offset of each slot in shown
+	offsets []int
+	// indexes holds the index edits at each slot apply to
+	indexes []int
+	dir     Direction
+}
+
+// NewCaret creates a caret for the line s.
+func NewCaret(s string) *Caret {
+	glyphs, dir := visualGlyphs(s)
+	c := &Caret{
+		str:     s,
+		glyphs:  glyphs,
+		offsets: make([]int, len(glyphs)+1),
+		indexes: make([]int, len(glyphs)+1),
+		dir:     dir,
+	}
+	buf := make([]byte, 0, len(s))
+	for i, g := range glyphs {
+		c.offsets[i] = len(buf)
+		buf = utf8.AppendRune(buf, g.r)
+	}
+	c.offsets[len(glyphs)] = len(buf)
+	c.shown = string(buf)
+
+	for i := range c.indexes {
+		c.indexes[i] = -1
+	}
+	// each index claims the slot it is displayed at, earliest first
+	for i := range s {
+		if slot := c.Slot(i); c.indexes[slot] < 0 {
+			c.indexes[slot] = i
+		}
+	}
+	if slot := c.Slot(len(s)); c.indexes[slot] < 0 {
+		c.indexes[slot] = len(s)
+	}
+	// the remaining slots edit next to whichever character beside them
+	// reads toward them
+	for slot, idx := range c.indexes {
+		if idx >= 0 {
+			continue
+		}
+		switch {
+		case slot < len(glyphs) && !glyphs[slot].rtl:
+			c.indexes[slot] = glyphs[slot].index
+		case slot > 0 && glyphs[slot-1].rtl:
+			c.indexes[slot] = glyphs[slot-1].index
+		case slot > 0:
+			c.indexes[slot] = glyphs[slot-1].end()
+		default:
+			c.indexes[slot] = glyphs[slot].end()
+		}
+	}
+	return c
+}
+
+// Len returns the number of characters displayed, the last slot.
+func (c *Caret) Len() int {
+	return len(c.glyphs)
+}
+
+// Visual returns the line in display order.
+func (c *Caret) Visual() string {
+	return c.shown
+}
+
+// Direction returns the direction of the line.
+func (c *Caret) Direction() Direction {
+	return c.dir
+}
+
+// Slot returns the slot a cursor before index i is displayed at. Left to right
+// characters are displayed right of the cursor before them, and right to left
+// characters left of it.
+func (c *Caret) Slot(i int) int {
+	if i >= len(c.str) {
+		// the end of the line follows its last character
+		last := -1
+		for slot, g := range c.glyphs {
+			if last < 0 || g.index > c.glyphs[last].index {
+				last = slot
+			}
+		}
+		switch {
+		case last < 0:
+			return 0
+		case c.glyphs[last].rtl:
+			return last
+		default:
+			return last + 1
+		}
+	}
+	if i < 0 {
+		i = 0
+	}
+	// round down to the start of the character i is in
+	for i > 0 && !utf8.RuneStart(c.str[i]) {
+		i--
+	}
+	for slot, g := range c.glyphs {
+		if g.index == i {
+			if g.rtl {
+				return slot + 1
+			}
+			return slot
+		}
+	}
+	return 0
+}
+
+// Index returns the index text typed at slot is inserted at.
+func (c *Caret) Index(slot int) int {
+	return c.indexes[c.clamp(slot)]
+}
+
+// Move returns the slot delta characters right of slot, or left for a negative
+// delta, stopping at either end of the line.
+func (c *Caret) Move(slot, delta int) int {
+	return c.clamp(slot + delta)
+}
+
+// X returns how far from the left of the displayed line slot is, measured
+// with m.
+func (c *Caret) X(m Measurer, slot int) float64 {
+	return fixedToFloat(m.MeasureString(c.shown[:c.offsets[c.clamp(slot)]]))
+}
+
+// SlotAt returns the slot nearest to x pixels from the left of the displayed
+// line, measured with m.
+func (c *Caret) SlotAt(m Measurer, x float64) int {
+	best, bestDist := 0, -1.0
+	for slot := range c.offsets {
+		dist := c.X(m, slot) - x
+		if dist < 0 {
+			dist = -dist
+		}
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = slot, dist
+		}
+	}
+	return best
+}
+
+func (c *Caret) clamp(slot int) int {
+	if slot < 0 {
+		return 0
+	}
+	if slot > len(c.glyphs) {
+		return len(c.glyphs)
+	}
+	return slot
+}
+
+// A visualGlyph is a character as it is displayed, with the index of the
+// character it displays.
+type visualGlyph struct {
+	r     rune
+	index int
+	size  int
+	rtl   bool
+}
+
+func (g visualGlyph) end() int {
+	return g.index + g.size
+}
+
+// visualGlyphs returns the characters of s in the order they are displayed in,
+// and the direction of the first paragraph of s.
+func visualGlyphs(s string) ([]visualGlyph, Direction) {
+	glyphs := make([]visualGlyph, 0, len(s))
+	dir := ParagraphDirection(s)
+	start := 0
+	for i, r := range s {
+		p, size := bidi.LookupRune(r)
+		if p.Class() != bidi.B {
+			continue
+		}
+		// paragraph separators stay where they are
+		glyphs = appendParagraph(glyphs, s[start:i], start)
+		glyphs = append(glyphs, visualGlyph{r: r, index: i, size: size})
+		start = i + size
+	}
+	return appendParagraph(glyphs, s[start:], start), dir
+}
+
+type bidiRun struct {
+	start, end int
+	rtl        bool
+	level      int
+}
+
+// appendParagraph appends the characters of the paragraph para, which starts
+// at offset in the full string, in display order.
+func appendParagraph(glyphs []visualGlyph, para string, offset int) []visualGlyph {
+	if para == "" {
+		return glyphs
+	}
+	runs := paragraphRuns(para)
+
+	// reverse each sequence of runs at or above each level, from the highest
+	// level down to the lowest odd level (rule L2)
+	maxLevel := 0
+	for _, r := range runs {
+		if r.level > maxLevel {
+			maxLevel = r.level
+		}
+	}
+	for level := maxLevel; level >= 1; level-- {
+		for i := 0; i < len(runs); {
+			if runs[i].level < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(runs) && runs[j].level >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				runs[a], runs[b] = runs[b], runs[a]
+			}
+			i = j
+		}
+	}
+
+	for _, run := range runs {
+		text := para[run.start:run.end]
+		if !run.rtl {
+			for i, r := range text {
+				glyphs = append(glyphs, visualGlyph{r: r, index: offset + run.start + i, size: utf8.RuneLen(r)})
+			}
+			continue
+		}
+		for end := len(text); end > 0; {
+			r, size := utf8.DecodeLastRuneInString(text[:end])
+			end -= size
+			if p, _ := bidi.LookupRune(r); p.IsBracket() {
+				r, _ = utf8.DecodeRuneInString(bidi.ReverseString(string(r)))
+			}
+			glyphs = append(glyphs, visualGlyph{r: r, index: offset + run.start + end, size: size, rtl: true})
+		}
+	}
+	return glyphs
+}
+
+// paragraphRuns splits para into runs of one direction, in logical order, with
+// the embedding level each is displayed at.
+func paragraphRuns(para string) []bidiRun {
+	var p bidi.Paragraph
+	order, err := func() (bidi.Ordering, error) {
+		if _, err := p.SetString(para); err != nil {
+			return bidi.Ordering{}, err
+		}
+		return p.Order()
+	}()
+	if err != nil || order.NumRuns() == 0 {
+		return []bidiRun{{end: len(para)}}
+	}
+
+	// runs are positioned by rune, so find where each rune starts
+	starts := make([]int, 0, len(para)+1)
+	for i := range para {
+		starts = append(starts, i)
+	}
+	starts = append(starts, len(para))
+
+	runs := make([]bidiRun, order.NumRuns())
+	for i := range runs {
+		run := order.Run(i)
+		first, last := run.Pos()
+		runs[i] = bidiRun{
+			start: starts[first],
+			end:   starts[last+1],
+			rtl:   run.Direction() == bidi.RightToLeft,
+		}
+	}
+
+	// runs only record the direction of their level, so restore levels: right
+	// to left runs embed one level into a left to right paragraph, and left to
+	// right runs two levels into a right to left paragraph. Numbers between
+	// right to left runs are also embedded two levels, so that they stay in
+	// place within the right to left text around them.
+	rtlPara := ParagraphDirection(para) == RightToLeft
+	for i := range runs {
+		switch {
+		case runs[i].rtl:
+			runs[i].level = 1
+		case rtlPara:
+			runs[i].level = 2
+		case i > 0 && i < len(runs)-1 && runs[i-1].rtl && runs[i+1].rtl && isNumeric(para[runs[i].start:runs[i].end]):
+			runs[i].level = 2
+		}
+	}
+	return runs
+}
+
+// isNumeric reports whether s holds only numbers and the characters which
+// separate and qualify them.
+func isNumeric(s string) bool {
+	for _, r := range s {
+		p, _ := bidi.LookupRune(r)
+		switch p.Class() {
+		case bidi.EN, bidi.AN, bidi.ES, bidi.ET, bidi.CS, bidi.NSM, bidi.BN:
+		default:
+			return false
+		}
+	}
+	return true
+}
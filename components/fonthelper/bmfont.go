@@ -0,0 +1,379 @@
+package fonthelper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/png"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/oakmound/oak/v4/oakerr"
+	"github.com/oakmound/oak/v4/render"
+	"golang.org/x/image/math/fixed"
+)
+
+// A Measurer measures text. Both *render.Font and *BitmapFont are Measurers.
+type Measurer interface {
+	// MeasureString returns the width s would be drawn at.
+	MeasureString(s string) fixed.Int26_6
+	// Height returns the height of a line of text.
+	Height() float64
+}
+
+var (
+	_ Measurer = &render.Font{}
+	_ Measurer = &BitmapFont{}
+)
+
+// A BitmapFont draws text from glyphs in page images, as described by an
+// AngelCode BMFont file.
+type BitmapFont struct {
+	// Face is the name of the font the bitmap font was generated from.
+	Face string
+	// Size is the size of the font the bitmap font was generated from.
+	Size int
+	// LineHeight is the distance in pixels from the top of one line to the
+	// next, and Base is the distance from the top of a line to its baseline.
+	LineHeight int
+	Base       int
+
+	pages   []image.Image
+	chars   map[rune]bmChar
+	kerning map[[2]rune]int
+	// tint, if set, replaces the color of the page images
+	tint *image.Uniform
+}
+
+type bmChar struct {
+	x, y, width, height int
+	xOffset, yOffset    int
+	xAdvance            int
+	page                int
+}
+
+// LoadBMFont loads the BMFont file at path, in text or binary format, along
+// with the page images it references relative to its directory.
+func LoadBMFont(file string) (*BitmapFont, error) {
+	return LoadBMFontFS(os.DirFS(filepath.Dir(file)), filepath.Base(file))
+}
+
+// LoadBMFontFS loads the BMFont file at file within fsys, along with the page
+// images it references relative to its directory.
+func LoadBMFontFS(fsys fs.FS, file string) (*BitmapFont, error) {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	dir := path.Dir(file)
+	return ParseBMFont(data, func(page string) (image.Image, error) {
+		f, err := fsys.Open(path.Join(dir, page))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		img, _, err := image.Decode(f)
+		return img, err
+	})
+}
+
+// ParseBMFont parses a BMFont file in text or binary format, calling loadPage
+// with the file name of each page image it references.
+func ParseBMFont(data []byte, loadPage func(file string) (image.Image, error)) (*BitmapFont, error) {
+	bf := &BitmapFont{
+		chars:   make(map[rune]bmChar),
+		kerning: make(map[[2]rune]int),
+	}
+	var (
+		pageFiles []string
+		err       error
+	)
+	if bytes.HasPrefix(data, []byte("BMF")) {
+		pageFiles, err = bf.parseBinary(data)
+	} else {
+		pageFiles, err = bf.parseText(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range pageFiles {
+		img, err := loadPage(file)
+		if err != nil {
+			return nil, err
+		}
+		bf.pages = append(bf.pages, img)
+	}
+	for r, c := range bf.chars {
+		if c.page < 0 || c.page >= len(bf.pages) {
+			return nil, fmt.Errorf("character %q references missing page %d", r, c.page)
+		}
+	}
+	return bf, nil
+}
+
+// maxBMPages is the most pages a BMFont can reference, as binary files store
+// each character's page in a byte.
+const maxBMPages = 256
+
+func (bf *BitmapFont) parseText(data []byte) ([]string, error) {
+	var pages []string
+	// pageCount is the number of pages the common line declares
+	pageCount := maxBMPages
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		tag, attrs := parseBMLine(sc.Text())
+		num := func(key string) int {
+			n, _ := strconv.Atoi(attrs[key])
+			return n
+		}
+		switch tag {
+		case "info":
+			bf.Face = attrs["face"]
+			bf.Size = num("size")
+			if bf.Size < 0 {
+				// negative sizes mark fonts generated to match character height
+				bf.Size = -bf.Size
+			}
+		case "common":
+			bf.LineHeight = num("lineHeight")
+			bf.Base = num("base")
+			if _, ok := attrs["pages"]; ok {
+				pageCount = min(num("pages"), maxBMPages)
+			}
+		case "page":
+			id := num("id")
+			if id < 0 || id >= pageCount {
+				return nil, oakerr.InvalidInput{InputName: "page id"}
+			}
+			for len(pages) <= id {
+				pages = append(pages, "")
+			}
+			pages[id] = attrs["file"]
+		case "char":
+			bf.chars[rune(num("id"))] = bmChar{
+				x:        num("x"),
+				y:        num("y"),
+				width:    num("width"),
+				height:   num("height"),
+				xOffset:  num("xoffset"),
+				yOffset:  num("yoffset"),
+				xAdvance: num("xadvance"),
+				page:     num("page"),
+			}
+		case "kerning":
+			bf.kerning[[2]rune{rune(num("first")), rune(num("second"))}] = num("amount")
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, oakerr.InvalidInput{InputName: "data"}
+	}
+	return pages, nil
+}
+
+// parseBMLine splits a line of a text BMFont file into its tag and its
+// key=value attributes, unquoting quoted values.
+func parseBMLine(line string) (string, map[string]string) {
+	attrs := make(map[string]string)
+	line = strings.TrimSpace(line)
+	i := strings.IndexAny(line, " \t")
+	if i < 0 {
+		return line, attrs
+	}
+	tag, rest := line[:i], line[i:]
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return tag, attrs
+		}
+		key := rest[:eq]
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			value = rest[1 : end+1]
+			rest = rest[min(end+2, len(rest)):]
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			value = rest[:end]
+			rest = rest[end:]
+		}
+		attrs[key] = value
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// BMFont binary block types
+const (
+	bmBlockInfo    = 1
+	bmBlockCommon  = 2
+	bmBlockPages   = 3
+	bmBlockChars   = 4
+	bmBlockKerning = 5
+)
+
+// sizes of the records in BMFont binary char and kerning blocks
+const (
+	bmCharSize    = 20
+	bmKerningSize = 10
+)
+
+func (bf *BitmapFont) parseBinary(data []byte) ([]string, error) {
+	if len(data) < 4 || data[3] != 3 {
+		return nil, oakerr.UnsupportedFormat{Format: "BMFont binary version"}
+	}
+	le := binary.LittleEndian
+	var pages []string
+	data = data[4:]
+	for len(data) > 0 {
+		if len(data) < 5 {
+			return nil, oakerr.InvalidInput{InputName: "data"}
+		}
+		kind, size := data[0], int(le.Uint32(data[1:5]))
+		data = data[5:]
+		if size > len(data) {
+			return nil, oakerr.InvalidInput{InputName: "data"}
+		}
+		block := data[:size]
+		data = data[size:]
+		switch kind {
+		case bmBlockInfo:
+			if len(block) < 14 {
+				return nil, oakerr.InvalidInput{InputName: "data"}
+			}
+			size := int(int16(le.Uint16(block[0:2])))
+			if size < 0 {
+				size = -size
+			}
+			bf.Size = size
+			bf.Face = string(bytes.TrimRight(block[14:], "\x00"))
+		case bmBlockCommon:
+			if len(block) < 4 {
+				return nil, oakerr.InvalidInput{InputName: "data"}
+			}
+			bf.LineHeight = int(le.Uint16(block[0:2]))
+			bf.Base = int(le.Uint16(block[2:4]))
+		case bmBlockPages:
+			for _, name := range bytes.Split(bytes.TrimRight(block, "\x00"), []byte{0}) {
+				pages = append(pages, string(name))
+			}
+		case bmBlockChars:
+			if len(block)%bmCharSize != 0 {
+				return nil, oakerr.InvalidInput{InputName: "data"}
+			}
+			for ; len(block) >= bmCharSize; block = block[bmCharSize:] {
+				bf.chars[rune(le.Uint32(block[0:4]))] = bmChar{
+					x:        int(le.Uint16(block[4:6])),
+					y:        int(le.Uint16(block[6:8])),
+					width:    int(le.Uint16(block[8:10])),
+					height:   int(le.Uint16(block[10:12])),
+					xOffset:  int(int16(le.Uint16(block[12:14]))),
+					yOffset:  int(int16(le.Uint16(block[14:16]))),
+					xAdvance: int(int16(le.Uint16(block[16:18]))),
+					page:     int(block[18]),
+				}
+			}
+		case bmBlockKerning:
+			if len(block)%bmKerningSize != 0 {
+				return nil, oakerr.InvalidInput{InputName: "data"}
+			}
+			for ; len(block) >= bmKerningSize; block = block[bmKerningSize:] {
+				pair := [2]rune{rune(le.Uint32(block[0:4])), rune(le.Uint32(block[4:8]))}
+				bf.kerning[pair] = int(int16(le.Uint16(block[8:10])))
+			}
+		}
+	}
+	if len(pages) == 0 {
+		return nil, oakerr.InvalidInput{InputName: "data"}
+	}
+	return pages, nil
+}
+
+// WithColor returns a copy of bf which draws its glyphs in c, using the page
+// images only for their alpha. This suits fonts generated as white glyphs.
+func (bf *BitmapFont) WithColor(c color.Color) *BitmapFont {
+	bf2 := *bf
+	bf2.tint = image.NewUniform(c)
+	return &bf2
+}
+
+// MeasureString returns the width s would be drawn at, including kerning.
+func (bf *BitmapFont) MeasureString(s string) fixed.Int26_6 {
+	width := 0
+	prev := rune(-1)
+	for _, r := range s {
+		c, ok := bf.chars[r]
+		if !ok {
+			continue
+		}
+		width += bf.kerning[[2]rune{prev, r}] + c.xAdvance
+		prev = r
+	}
+	return fixed.I(width)
+}
+
+// Height returns the font's line height.
+func (bf *BitmapFont) Height() float64 {
+	return float64(bf.LineHeight)
+}
+
+// drawString draws s with the top of its line at x, y.
+func (bf *BitmapFont) drawString(buff draw.Image, s string, x, y int) {
+	prev := rune(-1)
+	for _, r := range s {
+		c, ok := bf.chars[r]
+		if !ok {
+			continue
+		}
+		x += bf.kerning[[2]rune{prev, r}]
+		prev = r
+		dst := image.Rect(0, 0, c.width, c.height).Add(image.Pt(x+c.xOffset, y+c.yOffset))
+		src := image.Pt(c.x, c.y).Add(bf.pages[c.page].Bounds().Min)
+		if bf.tint != nil {
+			draw.DrawMask(buff, dst, bf.tint, image.Point{}, bf.pages[c.page], src, draw.Over)
+		} else {
+			draw.Draw(buff, dst, bf.pages[c.page], src, draw.Over)
+		}
+		x += c.xAdvance
+	}
+}
+
+// NewText creates a renderable text with the given string body, with the top
+// of its line at x, y.
+func (bf *BitmapFont) NewText(str string, x, y float64) *Text {
+	return bf.NewStringerText(textString(str), x, y)
+}
+
+// NewStrPtrText creates a renderable text which draws the string behind str.
+func (bf *BitmapFont) NewStrPtrText(str *string, x, y float64) *Text {
+	return bf.NewStringerText(textStringPtr{str}, x, y)
+}
+
+// NewStringerText creates a renderable text which draws the string provided
+// by str each frame.
+func (bf *BitmapFont) NewStringerText(str fmt.Stringer, x, y float64) *Text {
+	return newText(bf, str, x, y)
+}
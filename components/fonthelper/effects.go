@@ -0,0 +1,339 @@
+package fonthelper
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+
+	"github.com/oakmound/oak/v4/render"
+	"golang.org/x/image/font"
+)
+
+// An Effect changes how the text of an EffectText is drawn.
+type Effect func(*effectSet)
+
+type effectSet struct {
+	outline *outlineEffect
+	shadow  *shadowEffect
+	glow    *glowEffect
+	fill    Fill
+}
+
+type outlineEffect struct {
+	width int
+	color color.Color
+}
+
+type shadowEffect struct {
+	offset image.Point
+	blur   int
+	color  color.Color
+}
+
+type glowEffect struct {
+	radius int
+	color  color.Color
+}
+
+// WithOutline strokes the outside of the text's glyphs width pixels thick.
+func WithOutline(width int, c color.Color) Effect {
+	return func(es *effectSet) {
+		es.outline = &outlineEffect{width: width, color: c}
+	}
+}
+
+// WithShadow draws a copy of the text offset by dx, dy pixels, blurred by
+// blur pixels.
+func WithShadow(dx, dy, blur int, c color.Color) Effect {
+	return func(es *effectSet) {
+		es.shadow = &shadowEffect{offset: image.Pt(dx, dy), blur: blur, color: c}
+	}
+}
+
+// WithGlow draws a soft glow extending radius pixels out from the text.
+func WithGlow(radius int, c color.Color) Effect {
+	return func(es *effectSet) {
+		es.glow = &glowEffect{radius: radius, color: c}
+	}
+}
+
+// padding returns how far outside the text the effects may draw.
+func (es effectSet) padding() int {
+	pad := 0
+	if es.outline != nil {
+		pad = maxInt(pad, es.outline.width+1)
+	}
+	if es.shadow != nil {
+		off := maxInt(absInt(es.shadow.offset.X), absInt(es.shadow.offset.Y))
+		pad = maxInt(pad, off+es.shadow.blur)
+	}
+	if es.glow != nil {
+		pad = maxInt(pad, es.glow.radius+es.glow.radius/2+1)
+	}
+	return pad
+}
+
+// An EffectText is a text drawn with effects such as outlines and shadows.
+// The text and its effects are rendered again whenever its string changes, so
+// it can be updated as any other text is, e.g. with SetString or SetStringPtr.
+//
+// An EffectText is drawn and measured from its position down to the descent
+// of its font, widened to fit any glyphs reaching past that area, with a
+// margin the size of its effects around it.
+type EffectText struct {
+	*render.Text
+
+	effects effectSet
+
+	lock    sync.Mutex
+	metrics FontMetrics
+	str     string
+	// offset is where the sprite is drawn relative to the text's position
+	offset image.Point
+	sprite *render.Sprite
+}
+
+// NewEffectText creates a text drawing str from f at x, y with effects.
+func NewEffectText(f *render.Font, str string, x, y float64, effects ...Effect) *EffectText {
+	et := &EffectText{
+		Text:    f.NewText(str, x, y),
+		metrics: Metrics(f),
+	}
+	for _, e := range effects {
+		e(&et.effects)
+	}
+	et.refresh()
+	return et
+}
+
+// SetFont sets the font the text and its effects are drawn with.
+func (et *EffectText) SetFont(f *render.Font) {
+	et.lock.Lock()
+	defer et.lock.Unlock()
+	et.Text.SetFont(f)
+	et.metrics = Metrics(f)
+	et.sprite = nil
+}
+
+// refresh renders the text again if its string has changed. It must be called
+// with the EffectText's lock held, or before the EffectText is shared.
+func (et *EffectText) refresh() {
+	str := et.StringLiteral()
+	if et.sprite != nil && str == et.str {
+		return
+	}
+	et.str = str
+	pad := et.effects.padding()
+
+	// a render.Text draws its baseline a whole font size below its position
+	m := et.metrics
+	baseline := math.Floor(m.Size)
+	ink, advance := font.BoundString(m.face, str)
+	left := math.Min(0, fixedToFloat(ink.Min.X))
+	right := math.Max(fixedToFloat(advance), fixedToFloat(ink.Max.X))
+	top := math.Min(0, baseline-math.Max(m.Ascent, -fixedToFloat(ink.Min.Y)))
+	bottom := baseline + math.Max(m.Descent, fixedToFloat(ink.Max.Y))
+	area := image.Rect(int(math.Floor(left)), int(math.Floor(top)), int(math.Ceil(right)), int(math.Ceil(bottom)))
+	et.offset = area.Min.Sub(image.Pt(pad, pad))
+	bounds := image.Rect(0, 0, area.Dx()+2*pad, area.Dy()+2*pad)
+
+	text := image.NewRGBA(bounds)
+	// the text truncates its position to whole pixels, so aim for the middle
+	// of the pixel it should start at
+	et.Text.Draw(text, 0.5-float64(et.offset.X)-et.X(), 0.5-float64(et.offset.Y)-et.Y())
+	mask := image.NewAlpha(bounds)
+	draw.Draw(mask, bounds, text, image.Point{}, draw.Src)
+	if et.effects.fill != nil {
+		box := inkBounds(mask)
+		filled := image.NewRGBA(bounds)
+		draw.DrawMask(filled, box, et.effects.fill(box), box.Min, mask, box.Min, draw.Over)
+		text = filled
+	}
+
+	out := image.NewRGBA(bounds)
+	if s := et.effects.shadow; s != nil {
+		shadow := shiftAlpha(blurAlpha(mask, s.blur), s.offset)
+		fillMask(out, shadow, s.color)
+	}
+	if g := et.effects.glow; g != nil {
+		glow := blurAlpha(dilateAlpha(mask, g.radius/2), g.radius)
+		for i, a := range glow.Pix {
+			glow.Pix[i] = uint8(math.Min(255, float64(a)*1.5))
+		}
+		fillMask(out, glow, g.color)
+	}
+	if o := et.effects.outline; o != nil {
+		fillMask(out, dilateAlpha(mask, o.width), o.color)
+	}
+	draw.Draw(out, bounds, text, image.Point{}, draw.Over)
+	et.sprite = render.NewSprite(0, 0, out)
+}
+
+// Draw the text and its effects, rendering them again if the text's string
+// has changed.
+func (et *EffectText) Draw(buff draw.Image, xOff, yOff float64) {
+	et.lock.Lock()
+	defer et.lock.Unlock()
+	et.refresh()
+	et.sprite.Draw(buff, et.X()+xOff+float64(et.offset.X), et.Y()+yOff+float64(et.offset.Y))
+}
+
+// GetDims returns the size of the text and its effects.
+func (et *EffectText) GetDims() (int, int) {
+	et.lock.Lock()
+	defer et.lock.Unlock()
+	et.refresh()
+	return et.sprite.GetDims()
+}
+
+// ToSprite returns a sprite of the text and its effects as they are currently
+// drawn. The sprite's position is offset from the text to the top left of its
+// glyphs and effects, so it draws in the same place.
+func (et *EffectText) ToSprite() *render.Sprite {
+	et.lock.Lock()
+	defer et.lock.Unlock()
+	et.refresh()
+	sp := et.sprite.Copy().(*render.Sprite)
+	sp.SetPos(et.X()+float64(et.offset.X), et.Y()+float64(et.offset.Y))
+	return sp
+}
+
+// inkBounds returns the smallest rectangle containing every pixel of a that is
+// not fully transparent.
+func inkBounds(a *image.Alpha) image.Rectangle {
+	var ink image.Rectangle
+	b := a.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if a.AlphaAt(x, y).A != 0 {
+				ink = ink.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return ink
+}
+
+// fillMask draws c onto dst through mask.
+func fillMask(dst draw.Image, mask *image.Alpha, c color.Color) {
+	draw.DrawMask(dst, dst.Bounds(), image.NewUniform(c), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// dilateAlpha grows the opaque parts of a by radius pixels, anti-aliasing the
+// new edge.
+func dilateAlpha(a *image.Alpha, radius int) *image.Alpha {
+	if radius <= 0 {
+		return a
+	}
+	type offset struct {
+		dx, dy int
+		weight float64
+	}
+	var disk []offset
+	edge := float64(radius) + 0.5
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			dist := math.Hypot(float64(dx), float64(dy))
+			if dist < edge {
+				disk = append(disk, offset{dx, dy, math.Min(1, edge-dist)})
+			}
+		}
+	}
+	b := a.Bounds()
+	out := image.NewAlpha(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			max := 0.0
+			for _, o := range disk {
+				p := image.Pt(x+o.dx, y+o.dy)
+				if !p.In(b) {
+					continue
+				}
+				if v := float64(a.AlphaAt(p.X, p.Y).A) * o.weight; v > max {
+					max = v
+				}
+			}
+			out.SetAlpha(x, y, color.Alpha{A: uint8(max)})
+		}
+	}
+	return out
+}
+
+// blurAlpha approximates a gaussian blur spreading a by radius pixels with
+// three box blurs.
+func blurAlpha(a *image.Alpha, radius int) *image.Alpha {
+	if radius <= 0 {
+		return a
+	}
+	b := a.Bounds()
+	w, h := b.Dx(), b.Dy()
+	buf := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			buf[y*w+x] = float64(a.AlphaAt(b.Min.X+x, b.Min.Y+y).A)
+		}
+	}
+	tmp := make([]float64, w*h)
+	boxRadius := (radius + 2) / 3
+	for pass := 0; pass < 3; pass++ {
+		boxBlur(tmp, buf, w, h, boxRadius, true)
+		boxBlur(buf, tmp, w, h, boxRadius, false)
+	}
+	out := image.NewAlpha(b)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out.SetAlpha(b.Min.X+x, b.Min.Y+y, color.Alpha{A: uint8(math.Round(buf[y*w+x]))})
+		}
+	}
+	return out
+}
+
+// boxBlur sets each value of dst to the average of the values within radius of
+// it in src, along rows if horizontal and along columns otherwise.
+func boxBlur(dst, src []float64, w, h, radius int, horizontal bool) {
+	lines, length, step, lineStep := h, w, 1, w
+	if !horizontal {
+		lines, length, step, lineStep = w, h, w, 1
+	}
+	n := float64(2*radius + 1)
+	for l := 0; l < lines; l++ {
+		base := l * lineStep
+		at := func(i int) float64 {
+			if i < 0 || i >= length {
+				return 0
+			}
+			return src[base+i*step]
+		}
+		sum := 0.0
+		for i := -radius; i <= radius; i++ {
+			sum += at(i)
+		}
+		for i := 0; i < length; i++ {
+			dst[base+i*step] = sum / n
+			sum += at(i+radius+1) - at(i-radius)
+		}
+	}
+}
+
+// shiftAlpha moves the contents of a by off, dropping what moves out of bounds.
+func shiftAlpha(a *image.Alpha, off image.Point) *image.Alpha {
+	b := a.Bounds()
+	out := image.NewAlpha(b)
+	draw.Draw(out, b.Add(off), a, b.Min, draw.Src)
+	return out
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
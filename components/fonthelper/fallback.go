@@ -0,0 +1,75 @@
+package fonthelper
+
+import (
+	"github.com/oakmound/oak/v4/oakerr"
+	"github.com/oakmound/oak/v4/render"
+)
+
+// A FallbackFont draws each character with the first of an ordered list of
+// fonts that has a glyph for it, in the color of the first font. Its embedded
+// Font can be passed anywhere a *render.Font is accepted.
+type FallbackFont struct {
+	*render.Font
+	fonts []*render.Font
+}
+
+// NewFallbackFont creates a FallbackFont from fonts, in order of preference.
+// Fallbacks already set on any of the fonts are tried directly after that font.
+func NewFallbackFont(fonts ...*render.Font) (*FallbackFont, error) {
+	if len(fonts) == 0 {
+		return nil, oakerr.InsufficientInputs{AtLeast: 1, InputName: "fonts"}
+	}
+	var chain []*render.Font
+	for _, f := range fonts {
+		chain = append(chain, f)
+		chain = append(chain, f.Fallbacks...)
+	}
+	return newFallbackFont(chain), nil
+}
+
+func newFallbackFont(chain []*render.Font) *FallbackFont {
+	primary := chain[0].Copy()
+	// copy the chain so that appending to the primary's fallbacks
+	// cannot modify it
+	primary.Fallbacks = append([]*render.Font{}, chain[1:]...)
+	return &FallbackFont{
+		Font:  primary,
+		fonts: chain,
+	}
+}
+
+// Fonts returns the fonts of ff, in the order they are tried.
+func (ff *FallbackFont) Fonts() []*render.Font {
+	return append([]*render.Font{}, ff.fonts...)
+}
+
+// RegenerateWith creates a new FallbackFont by regenerating each of ff's fonts
+// with fgFunc, e.g. to change the size of every font in the chain.
+func (ff *FallbackFont) RegenerateWith(fgFunc func(render.FontGenerator) render.FontGenerator) (*FallbackFont, error) {
+	chain := make([]*render.Font, len(ff.fonts))
+	for i, f := range ff.fonts {
+		regen, err := f.RegenerateWith(fgFunc)
+		if err != nil {
+			return nil, err
+		}
+		chain[i] = regen
+	}
+	return newFallbackFont(chain), nil
+}
+
+// RegenerateWithFallbacks regenerates f with fgFunc as f.RegenerateWith does,
+// but also regenerates and keeps f's fallbacks, which RegenerateWith drops.
+func RegenerateWithFallbacks(f *render.Font, fgFunc func(render.FontGenerator) render.FontGenerator) (*render.Font, error) {
+	regen, err := f.RegenerateWith(fgFunc)
+	if err != nil {
+		return nil, err
+	}
+	for _, fallback := range f.Fallbacks {
+		fb, err := fallback.RegenerateWith(fgFunc)
+		if err != nil {
+			return nil, err
+		}
+		regen.Fallbacks = append(regen.Fallbacks, fb)
+	}
+	return regen, nil
+}
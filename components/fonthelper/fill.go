@@ -0,0 +1,159 @@
+package fonthelper
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/oakmound/oak/v4/render"
+)
+
+// A Fill creates the image text is filled with, given the bounds of the text's
+// glyphs. Fonts draw their color image relative to each glyph, so an image
+// which varies across the text, like a gradient, needs to be applied with
+// WithFill to span the whole text.
+type Fill func(bounds image.Rectangle) image.Image
+
+// WithFill fills an EffectText's glyphs with the image f creates in place of
+// the font's color.
+func WithFill(f Fill) Effect {
+	return func(es *effectSet) {
+		es.fill = f
+	}
+}
+
+// LinearGradient fills text with colors spread evenly across it at angle, in
+// radians clockwise from left to right. With no colors, text is transparent.
+func LinearGradient(angle float64, colors ...color.Color) Fill {
+	stops := gradientStops(colors)
+	dx, dy := math.Cos(angle), math.Sin(angle)
+	return func(bounds image.Rectangle) image.Image {
+		w, h := float64(bounds.Dx()), float64(bounds.Dy())
+		return &gradient{
+			bounds: bounds,
+			stops:  stops,
+			at: func(x, y float64) float64 {
+				// project each point onto the gradient's direction, scaled
+				// so that the corners of bounds project to 0 and 1
+				extent := math.Abs(w*dx) + math.Abs(h*dy)
+				if extent == 0 {
+					return 0
+				}
+				return ((x-w/2)*dx+(y-h/2)*dy)/extent + 0.5
+			},
+		}
+	}
+}
+
+// RadialGradient fills text with colors spread evenly from the center of the
+// text out to its corners. With no colors, text is transparent.
+func RadialGradient(colors ...color.Color) Fill {
+	stops := gradientStops(colors)
+	return func(bounds image.Rectangle) image.Image {
+		w, h := float64(bounds.Dx()), float64(bounds.Dy())
+		radius := math.Hypot(w/2, h/2)
+		return &gradient{
+			bounds: bounds,
+			stops:  stops,
+			at: func(x, y float64) float64 {
+				if radius == 0 {
+					return 0
+				}
+				return math.Hypot(x-w/2, y-h/2) / radius
+			},
+		}
+	}
+}
+
+// TextureFill fills text with sp, tiled from the top left of the text.
+func TextureFill(sp *render.Sprite) Fill {
+	tex := sp.GetRGBA()
+	return func(bounds image.Rectangle) image.Image {
+		return &texture{bounds: bounds, tex: tex}
+	}
+}
+
+func gradientStops(colors []color.Color) []color.RGBA64 {
+	stops := make([]color.RGBA64, len(colors))
+	for i, c := range colors {
+		stops[i] = color.RGBA64Model.Convert(c).(color.RGBA64)
+	}
+	return stops
+}
+
+// A gradient interpolates between stops according to the position at returns
+// for each pixel, relative to the top left of bounds.
+type gradient struct {
+	bounds image.Rectangle
+	stops  []color.RGBA64
+	at     func(x, y float64) float64
+}
+
+func (g *gradient) ColorModel() color.Model {
+	return color.RGBA64Model
+}
+
+func (g *gradient) Bounds() image.Rectangle {
+	return g.bounds
+}
+
+func (g *gradient) At(x, y int) color.Color {
+	switch len(g.stops) {
+	case 0:
+		return color.RGBA64{}
+	case 1:
+		return g.stops[0]
+	}
+	// sample the center of the pixel
+	t := g.at(float64(x-g.bounds.Min.X)+0.5, float64(y-g.bounds.Min.Y)+0.5)
+	t = math.Max(0, math.Min(1, t)) * float64(len(g.stops)-1)
+	i := int(t)
+	if i >= len(g.stops)-1 {
+		return g.stops[len(g.stops)-1]
+	}
+	return lerpRGBA64(g.stops[i], g.stops[i+1], t-float64(i))
+}
+
+func lerpRGBA64(a, b color.RGBA64, t float64) color.RGBA64 {
+	lerp := func(a, b uint16) uint16 {
+		return uint16(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	return color.RGBA64{
+		R: lerp(a.R, b.R),
+		G: lerp(a.G, b.G),
+		B: lerp(a.B, b.B),
+		A: lerp(a.A, b.A),
+	}
+}
+
+// A texture repeats tex from the top left of bounds.
+type texture struct {
+	bounds image.Rectangle
+	tex    *image.RGBA
+}
+
+func (t *texture) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (t *texture) Bounds() image.Rectangle {
+	return t.bounds
+}
+
+func (t *texture) At(x, y int) color.Color {
+	tb := t.tex.Bounds()
+	if tb.Empty() {
+		return color.RGBA{}
+	}
+	tx := mod(x-t.bounds.Min.X, tb.Dx())
+	ty := mod(y-t.bounds.Min.Y, tb.Dy())
+	return t.tex.At(tb.Min.X+tx, tb.Min.Y+ty)
+}
+
+func mod(a, b int) int {
+	m := a % b
+	if m < 0 {
+		m += b
+	}
+	return m
+}
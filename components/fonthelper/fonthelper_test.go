@@ -0,0 +1,673 @@
+package fonthelper_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/oakmound/grove/components/fonthelper"
+	"github.com/oakmound/oak/v4/render"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	green = color.RGBA{0, 255, 0, 255}
+)
+
+func TestRegistry(t *testing.T) {
+	r := fonthelper.NewRegistry(2)
+	base := render.DefaultFont()
+	r.Register("default", base)
+	if _, err := r.Get("missing", 10, red); err == nil {
+		t.Fatal("expected error for unregistered font")
+	}
+
+	a := r.MustGet("default", 10, red)
+	if a.Height() != 10 {
+		t.Fatalf("expected variant of size 10, got %v", a.Height())
+	}
+	if got := r.MustGet("default", 10, color.RGBA64{0xffff, 0, 0, 0xffff}); got != a {
+		t.Fatal("expected variant with an equal color to be memoized")
+	}
+	b := r.MustGet("default", 12, red)
+	// use a so that b is the least recently used
+	r.MustGet("default", 10, red)
+	r.MustGet("default", 14, red)
+	if r.MustGet("default", 10, red) != a {
+		t.Fatal("expected recently used variant to be kept")
+	}
+	if r.MustGet("default", 12, red) == b {
+		t.Fatal("expected least recently used variant to be evicted")
+	}
+
+	kept := r.MustGet("default", 10, nil)
+	if kept.Src.At(0, 0) != base.Src.At(0, 0) {
+		t.Fatalf("expected nil color to keep the base color %v, got %v", base.Src.At(0, 0), kept.Src.At(0, 0))
+	}
+	if r.MustGet("default", 10, nil) != kept {
+		t.Fatal("expected base colored variant to be memoized")
+	}
+
+	r.Register("default", base.Copy())
+	if r.MustGet("default", 10, red) == a {
+		t.Fatal("expected Register to drop cached variants")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				if f := r.MustGet("default", float64(10+(i+j)%3), red); f == nil {
+					t.Error("expected a font")
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// alphaCount counts the pixels of img which are not fully transparent.
+func alphaCount(img *image.RGBA) int {
+	n := 0
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestFallbackFont(t *testing.T) {
+	if _, err := fonthelper.NewFallbackFont(); err == nil {
+		t.Fatal("expected error for no fonts")
+	}
+	primary := render.DefaultFont()
+	// the default font has no greek letters, which the go font does
+	fg := render.FontGenerator{
+		RawFile:     goregular.TTF,
+		Color:       image.White,
+		FontOptions: render.FontOptions{Size: primary.Height()},
+	}
+	fallback, err := fg.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ff, err := fonthelper.NewFallbackFont(primary, fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := primary.MeasureString("λ"); w != 0 {
+		t.Fatalf("expected primary font to have no glyph for λ, measured %v", w)
+	}
+	if w := ff.MeasureString("λ"); w == 0 || w != fallback.MeasureString("λ") {
+		t.Fatalf("expected λ measured by the fallback as %v, got %v", fallback.MeasureString("λ"), w)
+	}
+	if w := ff.MeasureString("aλ"); w != primary.MeasureString("a")+fallback.MeasureString("λ") {
+		t.Fatalf("expected mixed string measured by both fonts, got %v", w)
+	}
+	drawn := image.NewRGBA(image.Rect(0, 0, 30, 30))
+	ff.NewText("λ", 0, 0).Draw(drawn, 0, 0)
+	if alphaCount(drawn) == 0 {
+		t.Fatal("expected fallback to draw λ")
+	}
+	drawn = image.NewRGBA(image.Rect(0, 0, 30, 30))
+	primary.NewText("λ", 0, 0).Draw(drawn, 0, 0)
+	if alphaCount(drawn) != 0 {
+		t.Fatal("expected primary font alone to draw nothing for λ")
+	}
+
+	bigger, err := ff.RegenerateWith(fonthelper.WithSize(30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fonts := bigger.Fonts(); len(fonts) != 2 || fonts[0].Height() != 30 || fonts[1].Height() != 30 {
+		t.Fatalf("expected both fonts regenerated at size 30, got %d fonts", len(fonts))
+	}
+	if bigger.MeasureString("λ") <= ff.MeasureString("λ") {
+		t.Fatal("expected regenerated chain to measure λ larger")
+	}
+
+	regen, err := fonthelper.RegenerateWithFallbacks(ff.Font, fonthelper.WithSize(30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regen.Fallbacks) != 1 || regen.Fallbacks[0].Height() != 30 {
+		t.Fatalf("expected fallback kept at size 30, got %d fallbacks", len(regen.Fallbacks))
+	}
+	if regen.MeasureString("λ") != bigger.MeasureString("λ") {
+		t.Fatal("expected regenerated font to measure λ with its fallback")
+	}
+}
+
+// drawEffectText draws a new EffectText of s at 20, 20 with effects.
+func drawEffectText(s string, effects ...fonthelper.Effect) *image.RGBA {
+	et := fonthelper.NewEffectText(render.DefaultFont(), s, 20, 20, effects...)
+	buff := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	et.Draw(buff, 0, 0)
+	return buff
+}
+
+func TestEffectText(t *testing.T) {
+	plain := drawEffectText("Hi")
+	if alphaCount(plain) == 0 {
+		t.Fatal("expected plain text to draw")
+	}
+	effects := map[string]fonthelper.Effect{
+		"outline": fonthelper.WithOutline(2, red),
+		"shadow":  fonthelper.WithShadow(3, 3, 0, red),
+		"glow":    fonthelper.WithGlow(4, red),
+	}
+	for name, effect := range effects {
+		t.Run(name, func(t *testing.T) {
+			drawn := drawEffectText("Hi", effect)
+			outside := 0
+			for i := 3; i < len(drawn.Pix); i += 4 {
+				if plain.Pix[i] == 0 && drawn.Pix[i] != 0 {
+					outside++
+					if drawn.Pix[i-3] == 0 || drawn.Pix[i-1] != 0 {
+						t.Fatalf("expected effect pixel to be red, got %v", drawn.Pix[i-3:i+1])
+					}
+				}
+			}
+			if outside == 0 {
+				t.Fatal("expected effect to draw outside the text's glyphs")
+			}
+		})
+	}
+
+	et := fonthelper.NewEffectText(render.DefaultFont(), "a", 0, 0, fonthelper.WithOutline(1, red))
+	w, h := et.GetDims()
+	et.SetString("a longer string")
+	w2, h2 := et.GetDims()
+	if w2 <= w || h2 != h {
+		t.Fatalf("expected longer string to widen text from %v,%v, got %v,%v", w, h, w2, h2)
+	}
+	if sw, _ := et.ToSprite().GetDims(); sw != w2 {
+		t.Fatalf("expected sprite as wide as text %v, got %v", w2, sw)
+	}
+}
+
+func TestEffectText_Bounds(t *testing.T) {
+	fg := render.FontGenerator{
+		RawFile:     goregular.TTF,
+		Color:       image.White,
+		FontOptions: render.FontOptions{Size: 20},
+	}
+	f, err := fg.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ǻ reaches above the font's size and ascent, and g and j below the
+	// baseline
+	const s = "ǺÉgj"
+	plain := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	f.NewText(s, 20, 20).Draw(plain, 0, 0)
+	et := fonthelper.NewEffectText(f, s, 20, 20)
+	drawn := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	et.Draw(drawn, 0, 0)
+	if !bytes.Equal(plain.Pix, drawn.Pix) {
+		t.Fatalf("expected effect text without effects to draw as the plain text, drew %d of %d pixels",
+			alphaCount(drawn), alphaCount(plain))
+	}
+	sp := et.ToSprite()
+	fromSprite := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	sp.Draw(fromSprite, 0, 0)
+	if !bytes.Equal(plain.Pix, fromSprite.Pix) {
+		t.Fatal("expected sprite of effect text to draw as the text")
+	}
+	if sp.Y() >= 20 {
+		t.Fatalf("expected sprite to start above the text, got %v", sp.Y())
+	}
+	m := fonthelper.Metrics(f)
+	if _, h := et.GetDims(); float64(h) < m.Size+m.Descent {
+		t.Fatalf("expected text to be measured down to its descent, got height %d", h)
+	}
+
+	big, err := f.RegenerateWith(fonthelper.WithSize(30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, h := et.GetDims()
+	et.SetFont(big)
+	if _, h2 := et.GetDims(); h2 <= h {
+		t.Fatalf("expected larger font to grow text from height %d, got %d", h, h2)
+	}
+}
+
+func TestLinearGradient(t *testing.T) {
+	blue := color.RGBA{0, 0, 255, 255}
+	grad := fonthelper.LinearGradient(0, red, blue)(image.Rect(10, 0, 20, 1))
+	if r, _, b, _ := grad.At(10, 0).RGBA(); r <= b {
+		t.Fatal("expected first column of bounds to be red")
+	}
+	if r, _, b, _ := grad.At(19, 0).RGBA(); b <= r {
+		t.Fatal("expected last column of bounds to be blue")
+	}
+
+	drawn := drawEffectText("HH", fonthelper.WithFill(fonthelper.LinearGradient(0, red, blue)))
+	ink := image.Rectangle{}
+	b := drawn.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if drawn.RGBAAt(x, y).A != 0 {
+				ink = ink.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	// the most opaque pixel of a column shows its color best
+	column := func(x int) color.RGBA {
+		var c color.RGBA
+		for y := ink.Min.Y; y < ink.Max.Y; y++ {
+			if px := drawn.RGBAAt(x, y); px.A > c.A {
+				c = px
+			}
+		}
+		return c
+	}
+	if c := column(ink.Min.X); c.R <= c.B*4 {
+		t.Fatalf("expected first ink column to be red, got %v", c)
+	}
+	if c := column(ink.Max.X - 1); c.B <= c.R*4 {
+		t.Fatalf("expected last ink column to be blue, got %v", c)
+	}
+}
+
+func TestTextureFill(t *testing.T) {
+	tex := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	tex.Set(0, 0, red)
+	tex.Set(1, 0, green)
+	fill := fonthelper.TextureFill(render.NewSprite(0, 0, tex))(image.Rect(5, 5, 10, 8))
+	expected := map[image.Point]color.RGBA{
+		{5, 5}: red,
+		{6, 5}: green,
+		{7, 5}: red,
+		{8, 7}: green,
+		{9, 6}: red,
+	}
+	for p, c := range expected {
+		if got := color.RGBAModel.Convert(fill.At(p.X, p.Y)); got != c {
+			t.Fatalf("expected %v at %v, got %v", c, p, got)
+		}
+	}
+}
+
+// bmPage is a page image for testdata/test.fnt, with 'A' in red and 'B' in
+// green.
+func bmPage() image.Image {
+	page := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(page, image.Rect(0, 0, 4, 6), image.NewUniform(red), image.Point{}, draw.Src)
+	draw.Draw(page, image.Rect(4, 0, 7, 6), image.NewUniform(green), image.Point{}, draw.Src)
+	return page
+}
+
+func loadBMPage(t *testing.T) func(string) (image.Image, error) {
+	return func(file string) (image.Image, error) {
+		if file != "test.png" {
+			t.Fatalf("expected page test.png to be loaded, got %q", file)
+		}
+		return bmPage(), nil
+	}
+}
+
+// checkBMFont checks a font parsed from testdata/test.fnt or its binary
+// equivalent.
+func checkBMFont(t *testing.T, bf *fonthelper.BitmapFont) {
+	t.Helper()
+	if bf.Face != "Test" || bf.Size != 8 || bf.LineHeight != 10 || bf.Base != 8 {
+		t.Fatalf("unexpected font info %q %d %d %d", bf.Face, bf.Size, bf.LineHeight, bf.Base)
+	}
+	if w := bf.MeasureString("A"); w != fixed.I(5) {
+		t.Fatalf("expected A to advance 5, got %v", w)
+	}
+	// B is kerned one pixel closer to A
+	if w := bf.MeasureString("AB"); w != fixed.I(8) {
+		t.Fatalf("expected AB measured as 8 with kerning, got %v", w)
+	}
+	if w := bf.MeasureString("BA"); w != fixed.I(9) {
+		t.Fatalf("expected BA measured as 9 without kerning, got %v", w)
+	}
+	if w := bf.MeasureString("A?"); w != fixed.I(5) {
+		t.Fatalf("expected missing characters to be skipped, got %v", w)
+	}
+
+	buff := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	txt := bf.NewText("AB", 0, 0)
+	txt.Draw(buff, 0, 0)
+	expected := map[image.Point]color.RGBA{
+		// A's rect is drawn at its offset
+		{0, 2}: {},
+		{1, 1}: {},
+		{1, 2}: red,
+		{3, 7}: red,
+		{3, 8}: {},
+		// B follows A's advance less the kerning, overlapping its last column
+		{4, 2}: green,
+		{6, 7}: green,
+		{7, 2}: {},
+	}
+	for p, c := range expected {
+		if got := buff.RGBAAt(p.X, p.Y); got != c {
+			t.Errorf("expected %v at %v, got %v", c, p, got)
+		}
+	}
+	if w, h := txt.GetDims(); w != 8 || h != 10 {
+		t.Fatalf("expected text dims 8,10, got %d,%d", w, h)
+	}
+}
+
+func TestParseBMFont_Text(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "test.fnt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf, err := fonthelper.ParseBMFont(data, loadBMPage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBMFont(t, bf)
+
+	invalid := map[string]string{
+		"negative page":        `page id=-1 file="test.png"`,
+		"page past declared":   "common lineHeight=10 base=8 pages=1\npage id=1000 file=\"test.png\"",
+		"page past any binary": `page id=300 file="test.png"`,
+		"no pages":             "common lineHeight=10 base=8 pages=0",
+		"missing char page":    "page id=0 file=\"test.png\"\nchar id=65 page=1",
+	}
+	for name, data := range invalid {
+		if _, err := fonthelper.ParseBMFont([]byte(data), loadBMPage(t)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// bmBlock encodes a block of a binary BMFont file.
+func bmBlock(kind byte, fields ...interface{}) []byte {
+	var payload bytes.Buffer
+	for _, f := range fields {
+		binary.Write(&payload, binary.LittleEndian, f)
+	}
+	out := []byte{kind, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(out[1:], uint32(payload.Len()))
+	return append(out, payload.Bytes()...)
+}
+
+type bmBinaryChar struct {
+	ID                  uint32
+	X, Y, Width, Height uint16
+	XOffset, YOffset    int16
+	XAdvance            int16
+	Page, Channel       uint8
+}
+
+func TestParseBMFont_Binary(t *testing.T) {
+	var (
+		info    = bmBlock(1, int16(-8), [12]byte{}, []byte("Test\x00"))
+		common  = bmBlock(2, uint16(10), uint16(8), [11]byte{})
+		pages   = bmBlock(3, []byte("test.png\x00"))
+		chars   = bmBlock(4, bmBinaryChar{65, 0, 0, 4, 6, 1, 2, 5, 0, 15}, bmBinaryChar{66, 4, 0, 3, 6, 0, 2, 4, 0, 15})
+		kerning = bmBlock(5, uint32(65), uint32(66), int16(-1))
+		header  = []byte("BMF\x03")
+	)
+	file := func(parts ...[]byte) []byte {
+		return bytes.Join(append([][]byte{header}, parts...), nil)
+	}
+	bf, err := fonthelper.ParseBMFont(file(info, common, pages, chars, kerning), loadBMPage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBMFont(t, bf)
+
+	invalid := map[string][]byte{
+		"version":          []byte("BMF\x02"),
+		"truncated header": file(info, common, pages, chars[:3]),
+		"truncated block":  file(info, common, pages, chars[:len(chars)-1]),
+		"short info":       file(bmBlock(1, [4]byte{}), common, pages),
+		"short common":     file(info, bmBlock(2, uint16(10)), pages),
+		"partial char":     file(info, common, pages, bmBlock(4, bmBinaryChar{ID: 65}, [5]byte{})),
+		"partial kerning":  file(info, common, pages, chars, bmBlock(5, uint32(65), uint32(66))),
+		"no pages":         file(info, common, chars),
+	}
+	for name, data := range invalid {
+		if _, err := fonthelper.ParseBMFont(data, loadBMPage(t)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	fg := render.FontGenerator{
+		RawFile:     goregular.TTF,
+		Color:       image.White,
+		FontOptions: render.FontOptions{Size: 18},
+	}
+	goFont, err := fg.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fonts := map[string]*render.Font{
+		"default":   render.DefaultFont(),
+		"goregular": goFont,
+	}
+	for name, f := range fonts {
+		t.Run(name, func(t *testing.T) {
+			m := fonthelper.Metrics(f)
+			if m.Size != f.Height() {
+				t.Fatalf("expected size %v, got %v", f.Height(), m.Size)
+			}
+			if m.LineHeight != m.Ascent+m.Descent+m.LineGap {
+				t.Fatalf("expected line height %v to be ascent %v + descent %v + gap %v",
+					m.LineHeight, m.Ascent, m.Descent, m.LineGap)
+			}
+			if m.LineGap < 0 {
+				t.Fatalf("expected non-negative line gap, got %v", m.LineGap)
+			}
+			if !(0 < m.XHeight && m.XHeight < m.CapHeight && m.CapHeight <= m.Ascent) {
+				t.Fatalf("expected 0 < x height %v < cap height %v <= ascent %v",
+					m.XHeight, m.CapHeight, m.Ascent)
+			}
+			g, ok := m.Glyph('H')
+			if !ok {
+				t.Fatal("expected glyph for H")
+			}
+			if measured := float64(f.MeasureString("H")) / 64; g.Advance != measured {
+				t.Fatalf("expected advance of H %v to match measured %v", g.Advance, measured)
+			}
+			if g.Bounds.W() <= 0 || g.Bounds.Max.Y() > 0 {
+				t.Fatalf("expected H to have ink on and above the baseline, got %v", g.Bounds)
+			}
+		})
+	}
+}
+
+func TestAtlas(t *testing.T) {
+	f := render.DefaultFont()
+	a, err := fonthelper.NewAtlas(f, fonthelper.DefaultAtlasChars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Height() != f.Height() {
+		t.Fatalf("expected atlas height %v, got %v", f.Height(), a.Height())
+	}
+
+	fromFont := image.NewRGBA(image.Rect(0, 0, 200, 30))
+	f.NewText("Score: 1234", 2, 2).Draw(fromFont, 0, 0)
+	fromAtlas := image.NewRGBA(image.Rect(0, 0, 200, 30))
+	a.NewText("Score: 1234", 2, 2).Draw(fromAtlas, 0, 0)
+	// glyphs are drawn at whole pixels from the atlas, so allow for small
+	// differences in anti-aliasing
+	drawn, differ := 0, 0
+	for i := 3; i < len(fromFont.Pix); i += 4 {
+		if fromFont.Pix[i] != 0 {
+			drawn++
+		}
+		if d := int(fromFont.Pix[i]) - int(fromAtlas.Pix[i]); d > 64 || d < -64 {
+			differ++
+		}
+	}
+	if drawn == 0 {
+		t.Fatal("expected font to draw text")
+	}
+	if differ > drawn/10 {
+		t.Fatalf("atlas text differs from font text in %d of %d pixels", differ, drawn)
+	}
+
+	data, err := os.ReadFile(filepath.Join("testdata", "test.fnt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf, err := fonthelper.ParseBMFont(data, loadBMPage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt := a.NewText("AB", 0, 0)
+	txt.SetFont(bf)
+	if w, h := txt.GetDims(); w != 8 || h != 10 {
+		t.Fatalf("expected text moved to bitmap font to measure 8,10, got %d,%d", w, h)
+	}
+	txt.SetFont(a)
+	if w, h := txt.GetDims(); w != a.MeasureString("AB").Round() || h != int(a.Height()+0.5) {
+		t.Fatalf("expected text moved back to atlas to measure with it, got %d,%d", w, h)
+	}
+
+	if _, err := fonthelper.NewAtlas(f, ""); err == nil {
+		t.Fatal("expected error for atlas with no characters")
+	}
+}
+
+func TestVisual(t *testing.T) {
+	tcs := []struct {
+		logical, visual string
+		dir             fonthelper.Direction
+	}{
+		{"hello", "hello", fonthelper.LeftToRight},
+		{"abc אבג", "abc גבא", fonthelper.LeftToRight},
+		{"אבג 123 דה", "הד 123 גבא", fonthelper.RightToLeft},
+		{"a אב 12 גד b", "a דג 12 בא b", fonthelper.LeftToRight},
+		{"(אב)", "(בא)", fonthelper.RightToLeft},
+		{"אב\nab", "בא\nab", fonthelper.RightToLeft},
+	}
+	for _, tc := range tcs {
+		if got := fonthelper.Visual(tc.logical); got != tc.visual {
+			t.Errorf("Visual(%q): expected %q, got %q", tc.logical, tc.visual, got)
+		}
+		if got := fonthelper.ParagraphDirection(tc.logical); got != tc.dir {
+			t.Errorf("ParagraphDirection(%q): expected %v, got %v", tc.logical, tc.dir, got)
+		}
+	}
+}
+
+// runeMeasurer measures every character 10 pixels wide.
+type runeMeasurer struct{}
+
+func (runeMeasurer) MeasureString(s string) fixed.Int26_6 {
+	return fixed.I(10 * utf8.RuneCountInString(s))
+}
+
+func (runeMeasurer) Height() float64 {
+	return 10
+}
+
+func TestCaret(t *testing.T) {
+	// displayed as "abבא"
+	c := fonthelper.NewCaret("abאב")
+	if c.Visual() != "abבא" {
+		t.Fatalf("expected visual line %q, got %q", "abבא", c.Visual())
+	}
+	slots := map[int]int{0: 0, 1: 1, 2: 4, 4: 3, 6: 2}
+	for index, slot := range slots {
+		if got := c.Slot(index); got != slot {
+			t.Errorf("Slot(%d): expected %d, got %d", index, slot, got)
+		}
+		if got := c.Index(slot); got != index {
+			t.Errorf("Index(%d): expected %d, got %d", slot, index, got)
+		}
+	}
+	if got := c.Move(c.Slot(6), 1); got != 3 {
+		t.Errorf("expected moving right from the end to reach slot 3, got %d", got)
+	}
+	if got := c.Move(0, -1); got != 0 {
+		t.Errorf("expected moving left from slot 0 to stay there, got %d", got)
+	}
+	if got := c.X(runeMeasurer{}, 3); got != 30 {
+		t.Errorf("expected slot 3 at x 30, got %v", got)
+	}
+	if got := c.SlotAt(runeMeasurer{}, 28); got != 3 {
+		t.Errorf("expected x 28 nearest slot 3, got %d", got)
+	}
+
+	m := runeMeasurer{}
+	if got := fonthelper.AlignOffset(m, "abc", 100, fonthelper.AlignStart); got != 0 {
+		t.Errorf("expected left to right text to start at 0, got %v", got)
+	}
+	if got := fonthelper.AlignOffset(m, "אבג", 100, fonthelper.AlignStart); got != 70 {
+		t.Errorf("expected right to left text to start at 70, got %v", got)
+	}
+	if got := fonthelper.AlignOffset(m, "אבג", 100, fonthelper.AlignEnd); got != 0 {
+		t.Errorf("expected right to left text to end at 0, got %v", got)
+	}
+}
+
+func BenchmarkText_Font(b *testing.B) {
+	f := render.DefaultFont()
+	buff := image.NewRGBA(image.Rect(0, 0, 200, 30))
+	txt := f.NewText("", 0, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		txt.SetString("Score: " + strconv.Itoa(i))
+		txt.Draw(buff, 0, 0)
+	}
+}
+
+func BenchmarkText_Atlas(b *testing.B) {
+	a, err := fonthelper.NewAtlas(render.DefaultFont(), fonthelper.DefaultAtlasChars)
+	if err != nil {
+		b.Fatal(err)
+	}
+	buff := image.NewRGBA(image.Rect(0, 0, 200, 30))
+	txt := a.NewText("", 0, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		txt.SetString("Score: " + strconv.Itoa(i))
+		txt.Draw(buff, 0, 0)
+	}
+}
+
+func BenchmarkNewAtlas(b *testing.B) {
+	f := render.DefaultFont()
+	for i := 0; i < b.N; i++ {
+		fonthelper.NewAtlas(f, fonthelper.DefaultAtlasChars)
+	}
+}
+
+func BenchmarkNewText_Font(b *testing.B) {
+	f := render.DefaultFont()
+	buff := image.NewRGBA(image.Rect(0, 0, 200, 30))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.NewText("Score: "+strconv.Itoa(i), 0, 0).Draw(buff, 0, 0)
+	}
+}
+
+func BenchmarkNewText_Atlas(b *testing.B) {
+	a, err := fonthelper.NewAtlas(render.DefaultFont(), fonthelper.DefaultAtlasChars)
+	if err != nil {
+		b.Fatal(err)
+	}
+	buff := image.NewRGBA(image.Rect(0, 0, 200, 30))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.NewText("Score: "+strconv.Itoa(i), 0, 0).Draw(buff, 0, 0)
+	}
+}
@@ -2,11 +2,14 @@ module github.com/oakmound/grove/components/fonthelper
 
 go 1.18
 
-require github.com/oakmound/oak/v4 v4.0.2
+require (
+	github.com/oakmound/oak/v4 v4.0.2
+	golang.org/x/image v0.0.0-20220413100746-70e8d0d3baa9
+	golang.org/x/text v0.14.0
+)
 
 require (
 	github.com/disintegration/gift v1.2.1 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	golang.org/x/exp v0.0.0-20220414153411-bcd21879b8fd // indirect
-	golang.org/x/image v0.0.0-20220413100746-70e8d0d3baa9 // indirect
 )
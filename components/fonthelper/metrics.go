@@ -0,0 +1,92 @@
+package fonthelper
+
+import (
+	"math"
+
+	"github.com/oakmound/oak/v4/alg/floatgeom"
+	"github.com/oakmound/oak/v4/render"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// FontMetrics describes the dimensions of a font, in pixels.
+//
+// A render.Text draws its baseline Size pixels below its position, so its
+// glyphs reach from Size-Ascent to Size+Descent below it.
+type FontMetrics struct {
+	// Size is the size the font was generated at.
+	Size float64
+	// Ascent is the distance from the baseline to the top of the font's
+	// tallest glyphs, and Descent from the baseline to the bottom of its
+	// lowest. Both are positive.
+	Ascent, Descent float64
+	// LineGap is the space the font recommends between the descent of one
+	// line and the ascent of the next.
+	LineGap float64
+	// LineHeight is the distance the font recommends from one baseline to
+	// the next, the sum of Ascent, Descent and LineGap.
+	LineHeight float64
+	// CapHeight and XHeight are the heights above the baseline of flat
+	// capital letters, like 'H', and of lowercase letters, like 'x'.
+	CapHeight, XHeight float64
+
+	face font.Face
+}
+
+// GlyphMetrics describes the dimensions of one glyph, in pixels.
+type GlyphMetrics struct {
+	// Bounds are the glyph's ink bounds relative to its origin on the
+	// baseline; Y increases downward, so Bounds.Min.Y is negative for
+	// glyphs above the baseline.
+	Bounds floatgeom.Rect2
+	// Advance is how far the glyph moves the position of the next glyph.
+	Advance float64
+}
+
+// Metrics returns the metrics of f. Unless f is Unsafe, the result keeps its own
+// copy of f's face, so it can be used concurrently with drawing f.
+func Metrics(f *render.Font) FontMetrics {
+	face := f.Copy().Face
+	fm := face.Metrics()
+	m := FontMetrics{
+		Size:    f.Height(),
+		Ascent:  fixedToFloat(fm.Ascent),
+		Descent: fixedToFloat(fm.Descent),
+		face:    face,
+	}
+	m.LineHeight = math.Max(fixedToFloat(fm.Height), m.Ascent+m.Descent)
+	m.LineGap = m.LineHeight - m.Ascent - m.Descent
+	// the faces oak generates do not report cap or x heights, so measure
+	// them from representative glyphs
+	if g, ok := m.Glyph('H'); ok {
+		m.CapHeight = -g.Bounds.Min.Y()
+	}
+	if g, ok := m.Glyph('x'); ok {
+		m.XHeight = -g.Bounds.Min.Y()
+	}
+	return m
+}
+
+// Glyph returns the metrics of the glyph for r, if the font has one.
+func (m FontMetrics) Glyph(r rune) (GlyphMetrics, bool) {
+	bounds, advance, ok := m.face.GlyphBounds(r)
+	if !ok {
+		return GlyphMetrics{}, false
+	}
+	return GlyphMetrics{
+		Bounds: floatgeom.NewRect2(
+			fixedToFloat(bounds.Min.X), fixedToFloat(bounds.Min.Y),
+			fixedToFloat(bounds.Max.X), fixedToFloat(bounds.Max.Y),
+		),
+		Advance: fixedToFloat(advance),
+	}, true
+}
+
+// Kern returns the adjustment to the space between a and b when b follows a.
+func (m FontMetrics) Kern(a, b rune) float64 {
+	return fixedToFloat(m.face.Kern(a, b))
+}
+
+func fixedToFloat(f fixed.Int26_6) float64 {
+	return float64(f) / 64
+}
@@ -0,0 +1,152 @@
+package fonthelper
+
+import (
+	"container/list"
+	"image"
+	"image/color"
+	"io/fs"
+	"sync"
+
+	"github.com/oakmound/oak/v4/oakerr"
+	"github.com/oakmound/oak/v4/render"
+)
+
+// DefaultRegistryCapacity is the number of font variants a Registry created
+// with a non-positive capacity will keep.
+const DefaultRegistryCapacity = 64
+
+// A Registry holds fonts registered by name, and generates and caches variants
+// of those fonts at different sizes and colors. It is safe for concurrent use.
+type Registry struct {
+	lock     sync.Mutex
+	fonts    map[string]*render.Font
+	variants map[variantKey]*list.Element
+	// recent orders variants from most to least recently used
+	recent   *list.List
+	capacity int
+}
+
+type variantKey struct {
+	name  string
+	size  float64
+	color color.RGBA64
+	// baseColor marks variants in the registered font's own color
+	baseColor bool
+}
+
+type variant struct {
+	key  variantKey
+	font *render.Font
+}
+
+// NewRegistry creates an empty Registry which keeps up to capacity of its most
+// recently used font variants.
+func NewRegistry(capacity int) *Registry {
+	if capacity <= 0 {
+		capacity = DefaultRegistryCapacity
+	}
+	return &Registry{
+		fonts:    make(map[string]*render.Font),
+		variants: make(map[variantKey]*list.Element),
+		recent:   list.New(),
+		capacity: capacity,
+	}
+}
+
+// Register adds a font under name, replacing any font already registered
+// under that name along with its cached variants.
+func (r *Registry) Register(name string, f *render.Font) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.fonts[name]; ok {
+		for e := r.recent.Front(); e != nil; {
+			next := e.Next()
+			if v := e.Value.(*variant); v.key.name == name {
+				r.recent.Remove(e)
+				delete(r.variants, v.key)
+			}
+			e = next
+		}
+	}
+	r.fonts[name] = f
+}
+
+// RegisterFile loads the TrueType font at path and registers it under name.
+func (r *Registry) RegisterFile(name, path string) error {
+	fg := render.FontGenerator{
+		File:  path,
+		Color: image.White,
+	}
+	f, err := fg.Generate()
+	if err != nil {
+		return err
+	}
+	r.Register(name, f)
+	return nil
+}
+
+// RegisterFS loads the TrueType font at path within fsys and registers it
+// under name.
+func (r *Registry) RegisterFS(name string, fsys fs.FS, path string) error {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return err
+	}
+	fg := render.FontGenerator{
+		RawFile: data,
+		Color:   image.White,
+	}
+	f, err := fg.Generate()
+	if err != nil {
+		return err
+	}
+	r.Register(name, f)
+	return nil
+}
+
+// Get returns the font registered under name at the given size and color. A
+// nil color keeps the color of the registered font. Variants are generated on
+// first use and cached, so repeated calls with the same arguments return the
+// same font.
+func (r *Registry) Get(name string, size float64, c color.Color) (*render.Font, error) {
+	key := variantKey{
+		name:      name,
+		size:      size,
+		baseColor: c == nil,
+	}
+	regen := WithSize(size)
+	if c != nil {
+		key.color = color.RGBA64Model.Convert(c).(color.RGBA64)
+		regen = WithSizeAndColor(size, c)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if e, ok := r.variants[key]; ok {
+		r.recent.MoveToFront(e)
+		return e.Value.(*variant).font, nil
+	}
+	base, ok := r.fonts[name]
+	if !ok {
+		return nil, oakerr.NotFound{InputName: name}
+	}
+	f, err := RegenerateWithFallbacks(base, regen)
+	if err != nil {
+		return nil, err
+	}
+	r.variants[key] = r.recent.PushFront(&variant{key: key, font: f})
+	for r.recent.Len() > r.capacity {
+		oldest := r.recent.Back()
+		r.recent.Remove(oldest)
+		delete(r.variants, oldest.Value.(*variant).key)
+	}
+	return f, nil
+}
+
+// MustGet calls Get, panicking on error.
+func (r *Registry) MustGet(name string, size float64, c color.Color) *render.Font {
+	f, err := r.Get(name, size, c)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
@@ -0,0 +1,83 @@
+package fonthelper
+
+import (
+	"fmt"
+	"image/draw"
+
+	"github.com/oakmound/oak/v4/render"
+)
+
+// A TextFont is a font that a Text can be drawn with. Both *BitmapFont and
+// *Atlas are TextFonts.
+type TextFont interface {
+	Measurer
+	// drawString draws s with the top of its line at x, y.
+	drawString(buff draw.Image, s string, x, y int)
+}
+
+var (
+	_ TextFont = &BitmapFont{}
+	_ TextFont = &Atlas{}
+)
+
+// A Text is a renderable that draws text with a TextFont.
+type Text struct {
+	render.LayeredPoint
+	text fmt.Stringer
+	font TextFont
+}
+
+type textString string
+
+func (s textString) String() string {
+	return string(s)
+}
+
+type textStringPtr struct {
+	s *string
+}
+
+func (sp textStringPtr) String() string {
+	if sp.s == nil {
+		return "nil"
+	}
+	return *sp.s
+}
+
+func newText(f TextFont, str fmt.Stringer, x, y float64) *Text {
+	return &Text{
+		LayeredPoint: render.NewLayeredPoint(x, y, 0),
+		text:         str,
+		font:         f,
+	}
+}
+
+// Draw the text at its position
+func (t *Text) Draw(buff draw.Image, xOff, yOff float64) {
+	t.font.drawString(buff, t.text.String(), int(t.X()+xOff), int(t.Y()+yOff))
+}
+
+// GetDims returns the width of the text and the height of its font.
+func (t *Text) GetDims() (int, int) {
+	return t.font.MeasureString(t.text.String()).Round(), int(t.font.Height() + 0.5)
+}
+
+// SetString sets the string to draw.
+func (t *Text) SetString(str string) {
+	t.text = textString(str)
+}
+
+// SetStringPtr has the text draw the string behind str.
+func (t *Text) SetStringPtr(str *string) {
+	t.text = textStringPtr{str}
+}
+
+// SetFont sets the font the text is drawn with.
+func (t *Text) SetFont(f TextFont) {
+	t.font = f
+}
+
+// StringLiteral returns the string the text is currently drawing.
+func (t *Text) StringLiteral() string {
+	return t.text.String()
+}
@@ -0,0 +1,42 @@
+package intswitch
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/oakmound/oak/v4/event"
+)
+
+// A SwitchChange is the payload of SwitchChanged, holding the key a switch had
+// and the key it changed to.
+type SwitchChange[K comparable] struct {
+	Old, New K
+}
+
+var (
+	changedLock   sync.Mutex
+	changedEvents = map[reflect.Type]interface{}{}
+)
+
+// SwitchChanged returns the event a KeyedSwitch[K] triggers when its key
+// changes, if it has been given a trigger with SetChangeTrigger. Events are
+// registered once per key type, so every call for one K returns the same
+// event.
+func SwitchChanged[K comparable]() event.EventID[SwitchChange[K]] {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	changedLock.Lock()
+	defer changedLock.Unlock()
+	if ev, ok := changedEvents[t]; ok {
+		return ev.(event.EventID[SwitchChange[K]])
+	}
+	ev := event.RegisterEvent[SwitchChange[K]]()
+	changedEvents[t] = ev
+	return ev
+}
+
+func triggerChanged[K comparable](h event.Handler, cid event.CallerID, old, new K) {
+	if h == nil || old == new {
+		return
+	}
+	event.TriggerForCallerOn(h, cid, SwitchChanged[K](), SwitchChange[K]{Old: old, New: new})
+}
@@ -0,0 +1,172 @@
+package intswitch
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/oakmound/oak/v4/alg/floatgeom"
+	"github.com/oakmound/oak/v4/entities"
+	"github.com/oakmound/oak/v4/event"
+	"github.com/oakmound/oak/v4/oakerr"
+	"github.com/oakmound/oak/v4/render"
+	"github.com/oakmound/oak/v4/render/mod"
+)
+
+// A Direction is a way a DirectionalSwitch can face. Directions run clockwise
+// on screen from Right, 45 degrees apart.
+type Direction int
+
+// Directions a DirectionalSwitch can face.
+const (
+	Right Direction = iota
+	DownRight
+	Down
+	DownLeft
+	Left
+	UpLeft
+	Up
+	UpRight
+)
+
+var directionNames = [...]string{"Right", "DownRight", "Down", "DownLeft", "Left", "UpLeft", "Up", "UpRight"}
+
+func (d Direction) String() string {
+	if d < 0 || int(d) >= len(directionNames) {
+		return "Direction(" + strconv.Itoa(int(d)) + ")"
+	}
+	return directionNames[d]
+}
+
+// mirror returns the direction d faces when flipped horizontally.
+func (d Direction) mirror() Direction {
+	return (12 - d) % 8
+}
+
+// DefaultHysteresis is how many degrees past the edge of its facing's range a
+// DirectionalSwitch's movement must turn before it changes facing.
+const DefaultHysteresis = 10
+
+// A DirectionalSwitch is a switch between sprites for 4 or 8 directions, which
+// faces the direction its owner is moving. It only changes facing once the
+// movement has clearly turned into another direction's range, so movement
+// along the border between two directions does not flicker between them, and
+// it keeps its last facing when the movement stops.
+type DirectionalSwitch struct {
+	*KeyedSwitch[Direction]
+
+	eightWay   bool
+	hysteresis float64
+	idleSpeed  float64
+}
+
+// A DirectionalOption changes how a DirectionalSwitch picks its facing.
+type DirectionalOption func(*DirectionalSwitch)
+
+// WithHysteresis sets how many degrees past the edge of its facing's range
+// movement must turn before the switch changes facing.
+func WithHysteresis(degrees float64) DirectionalOption {
+	return func(ds *DirectionalSwitch) {
+		ds.hysteresis = degrees
+	}
+}
+
+// WithIdleSpeed sets the speed at or below which the switch is considered not
+// to be moving, keeping its facing. It defaults to 0.
+func WithIdleSpeed(speed float64) DirectionalOption {
+	return func(ds *DirectionalSwitch) {
+		ds.idleSpeed = speed
+	}
+}
+
+// NewDirectional creates a DirectionalSwitch facing start. Given renderables
+// for Up, Down, Left and Right it faces 4 directions, and given renderables
+// for the diagonals as well it faces 8. Any missing left facing renderables
+// are generated by flipping the right facing ones with mod.FlipX.
+func NewDirectional(start Direction, m map[Direction]render.Modifiable, opts ...DirectionalOption) (*DirectionalSwitch, error) {
+	subs := make(map[Direction]render.Modifiable, 8)
+	for d, r := range m {
+		subs[d] = r
+	}
+	for _, d := range []Direction{Right, DownRight, UpRight} {
+		if r, ok := subs[d]; ok {
+			if _, ok := subs[d.mirror()]; !ok {
+				subs[d.mirror()] = r.Copy().Modify(mod.FlipX)
+			}
+		}
+	}
+	ds := &DirectionalSwitch{
+		hysteresis: DefaultHysteresis,
+	}
+	for d := range subs {
+		if d%2 == 1 {
+			ds.eightWay = true
+		}
+	}
+	for d := Right; d <= UpRight; d++ {
+		if _, ok := subs[d]; !ok && (d%2 == 0 || ds.eightWay) {
+			return nil, oakerr.NotFound{InputName: "m:" + d.String()}
+		}
+	}
+	if _, ok := subs[start]; !ok {
+		return nil, oakerr.NotFound{InputName: "start:" + start.String()}
+	}
+	for _, opt := range opts {
+		opt(ds)
+	}
+	ds.KeyedSwitch = NewKeyed(start, subs)
+	return ds, nil
+}
+
+// Face turns the switch toward the direction of delta and returns its facing.
+func (ds *DirectionalSwitch) Face(delta floatgeom.Point2) Direction {
+	current := ds.Get()
+	if delta.Magnitude() <= ds.idleSpeed {
+		return current
+	}
+	step := 90.0
+	if ds.eightWay {
+		step = 45
+	}
+	angle := math.Atan2(delta.Y(), delta.X()) * 180 / math.Pi
+	if angleBetween(angle, float64(current)*45) <= step/2+ds.hysteresis {
+		return current
+	}
+	sectors := int(360 / step)
+	sector := (int(math.Round(angle/step)) + sectors) % sectors
+	d := Direction(sector * int(step/45))
+	ds.Set(d)
+	return d
+}
+
+// Follow has the switch face the direction of e's Delta every frame, until the
+// returned binding is unbound or e is destroyed.
+func (ds *DirectionalSwitch) Follow(h event.Handler, e *entities.Entity) event.Binding {
+	return h.UnsafeBind(event.Enter.UnsafeEventID, e.CID(), func(event.CallerID, event.Handler, interface{}) event.Response {
+		ds.Face(e.Delta)
+		return 0
+	})
+}
+
+// Copy creates a copy of the DirectionalSwitch
+func (ds *DirectionalSwitch) Copy() render.Modifiable {
+	newDs := *ds
+	newDs.KeyedSwitch = ds.KeyedSwitch.Copy().(*KeyedSwitch[Direction])
+	return &newDs
+}
+
+// Modify performs the input modifications on all elements of the
+// DirectionalSwitch
+func (ds *DirectionalSwitch) Modify(ms ...mod.Mod) render.Modifiable {
+	ds.KeyedSwitch.Modify(ms...)
+	return ds
+}
+
+// angleBetween returns the difference between two angles in degrees, from 0
+// to 180.
+func angleBetween(a, b float64) float64 {
+	diff := math.Mod(math.Abs(a-b), 360)
+	if diff > 180 {
+		diff = 360 - diff
+	}
+	return diff
+}
@@ -0,0 +1,270 @@
+package intswitch
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"sort"
+	"sync"
+
+	"github.com/oakmound/oak/v4/event"
+	"github.com/oakmound/oak/v4/oakerr"
+	"github.com/oakmound/oak/v4/render"
+	"github.com/oakmound/oak/v4/render/mod"
+)
+
+var _ render.Modifiable = &FlagSwitch{}
+
+// A FlagSwitch is keyed on individual flag bits, and draws every sub-component
+// whose bit is set in its current value, so that overlays like status effects
+// can be combined without a renderable for every combination.
+//
+// Sub-components are drawn in layer order, from the bottom up: the bits given
+// to SetOrder first, then any others from the lowest bit to the highest.
+type FlagSwitch struct {
+	render.LayeredPoint
+	subRenderables map[int]render.Modifiable
+	value          int
+	order          []int
+	customOrder    []int
+	lock           sync.RWMutex
+
+	changeHandler event.Handler
+	changeID      event.CallerID
+}
+
+// NewFlags creates a new FlagSwitch from a map of flag bits to modifiables. It
+// returns an error if any key is not a single bit.
+func NewFlags(value int, m map[int]render.Modifiable) (*FlagSwitch, error) {
+	for bit := range m {
+		if !isFlag(bit) {
+			return nil, oakerr.InvalidInput{InputName: "m:" + fmt.Sprint(bit)}
+		}
+	}
+	fs := &FlagSwitch{
+		LayeredPoint:   render.NewLayeredPoint(0, 0, 0),
+		subRenderables: m,
+		value:          value,
+	}
+	fs.reorder()
+	return fs, nil
+}
+
+// Add makes a new entry in the FlagSwitch's map. If the bit already existed,
+// it will be overwritten and an error will be returned. Keys which are not a
+// single bit are not added.
+func (fs *FlagSwitch) Add(bit int, v render.Modifiable) (err error) {
+	if !isFlag(bit) {
+		return oakerr.InvalidInput{InputName: "bit"}
+	}
+	fs.lock.Lock()
+	if _, ok := fs.subRenderables[bit]; ok {
+		err = oakerr.ExistingElement{
+			InputName:   "bit",
+			InputType:   "int",
+			Overwritten: true,
+		}
+	}
+	fs.subRenderables[bit] = v
+	fs.reorder()
+	fs.lock.Unlock()
+	return err
+}
+
+// SetOrder sets the layer order of bits, from the bottom up. Bits left out are
+// drawn above them, from the lowest bit to the highest.
+func (fs *FlagSwitch) SetOrder(bits ...int) {
+	fs.lock.Lock()
+	fs.customOrder = bits
+	fs.reorder()
+	fs.lock.Unlock()
+}
+
+// reorder sets the order sub-components are drawn in. It must be called with
+// the FlagSwitch's lock held, or before it is shared.
+func (fs *FlagSwitch) reorder() {
+	fs.order = fs.order[:0]
+	ordered := make(map[int]bool, len(fs.customOrder))
+	for _, bit := range fs.customOrder {
+		if _, ok := fs.subRenderables[bit]; ok && !ordered[bit] {
+			fs.order = append(fs.order, bit)
+			ordered[bit] = true
+		}
+	}
+	var rest []int
+	for bit := range fs.subRenderables {
+		if !ordered[bit] {
+			rest = append(rest, bit)
+		}
+	}
+	sort.Ints(rest)
+	fs.order = append(fs.order, rest...)
+}
+
+// Set sets the flags of the FlagSwitch, triggering SwitchChanged if they
+// changed.
+func (fs *FlagSwitch) Set(value int) {
+	fs.update(func(int) int { return value })
+}
+
+// Enable sets the given bits, leaving the others as they are.
+func (fs *FlagSwitch) Enable(bits int) {
+	fs.update(func(v int) int { return v | bits })
+}
+
+// Disable clears the given bits, leaving the others as they are.
+func (fs *FlagSwitch) Disable(bits int) {
+	fs.update(func(v int) int { return v &^ bits })
+}
+
+// Toggle flips the given bits, leaving the others as they are.
+func (fs *FlagSwitch) Toggle(bits int) {
+	fs.update(func(v int) int { return v ^ bits })
+}
+
+func (fs *FlagSwitch) update(fn func(int) int) {
+	fs.lock.Lock()
+	old := fs.value
+	fs.value = fn(old)
+	new := fs.value
+	h, cid := fs.changeHandler, fs.changeID
+	fs.lock.Unlock()
+	triggerChanged(h, cid, old, new)
+}
+
+// Get returns the FlagSwitch's current flags
+func (fs *FlagSwitch) Get() int {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	return fs.value
+}
+
+// Has returns whether every one of bits is set.
+func (fs *FlagSwitch) Has(bits int) bool {
+	return fs.Get()&bits == bits
+}
+
+// SetChangeTrigger has the FlagSwitch trigger SwitchChanged[int] on cid
+// through h whenever its flags change. If h is nil, event.DefaultBus is used.
+func (fs *FlagSwitch) SetChangeTrigger(h event.Handler, cid event.CallerID) {
+	if h == nil {
+		h = event.DefaultBus
+	}
+	fs.lock.Lock()
+	fs.changeHandler = h
+	fs.changeID = cid
+	fs.lock.Unlock()
+}
+
+// GetSub returns a keyed Modifiable from this FlagSwitch's map
+func (fs *FlagSwitch) GetSub(bit int) render.Modifiable {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	return fs.subRenderables[bit]
+}
+
+// visible calls fn with each sub-component whose bit is set, in layer order.
+// It must be called with the FlagSwitch's lock held.
+func (fs *FlagSwitch) visible(fn func(render.Modifiable)) {
+	for _, bit := range fs.order {
+		if fs.value&bit != 0 {
+			fn(fs.subRenderables[bit])
+		}
+	}
+}
+
+// Draw draws each set sub-component at an offset from the FlagSwitch's
+// logical location
+func (fs *FlagSwitch) Draw(buff draw.Image, xOff float64, yOff float64) {
+	fs.lock.RLock()
+	fs.visible(func(r render.Modifiable) {
+		r.Draw(buff, fs.X()+xOff, fs.Y()+yOff)
+	})
+	fs.lock.RUnlock()
+}
+
+// GetDims returns the size of the area covered by the set sub-components,
+// from the FlagSwitch's position.
+func (fs *FlagSwitch) GetDims() (int, int) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	b := fs.bounds()
+	return b.Max.X, b.Max.Y
+}
+
+func (fs *FlagSwitch) bounds() image.Rectangle {
+	var b image.Rectangle
+	fs.visible(func(r render.Modifiable) {
+		w, h := r.GetDims()
+		b = b.Union(image.Rect(0, 0, w, h).Add(image.Pt(int(r.X()), int(r.Y()))))
+	})
+	return b
+}
+
+// GetRGBA returns the set sub-components drawn together
+func (fs *FlagSwitch) GetRGBA() *image.RGBA {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	b := fs.bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Max.X, b.Max.Y))
+	fs.visible(func(r render.Modifiable) {
+		r.Draw(rgba, 0, 0)
+	})
+	return rgba
+}
+
+// Modify performs the input modifications on all elements of the FlagSwitch
+func (fs *FlagSwitch) Modify(ms ...mod.Mod) render.Modifiable {
+	fs.lock.RLock()
+	for _, r := range fs.subRenderables {
+		r.Modify(ms...)
+	}
+	fs.lock.RUnlock()
+	return fs
+}
+
+// Filter filters all elements of the FlagSwitch with filters
+func (fs *FlagSwitch) Filter(filters ...mod.Filter) {
+	fs.lock.RLock()
+	for _, r := range fs.subRenderables {
+		r.Filter(filters...)
+	}
+	fs.lock.RUnlock()
+}
+
+// Copy creates a copy of the FlagSwitch
+func (fs *FlagSwitch) Copy() render.Modifiable {
+	newFs := new(FlagSwitch)
+	newFs.LayeredPoint = fs.LayeredPoint.Copy()
+	newFs.subRenderables = make(map[int]render.Modifiable)
+	fs.lock.RLock()
+	for k, v := range fs.subRenderables {
+		newFs.subRenderables[k] = v.Copy()
+	}
+	newFs.value = fs.value
+	newFs.customOrder = fs.customOrder
+	fs.lock.RUnlock()
+	newFs.reorder()
+	return newFs
+}
+
+// ShiftPos shifts the FlagSwitch's logical position
+func (fs *FlagSwitch) ShiftPos(x, y float64) {
+	fs.SetPos(fs.X()+x, fs.Y()+y)
+}
+
+// SetTriggerID sets the ID AnimationEnd will trigger on for animating subtypes.
+func (fs *FlagSwitch) SetTriggerID(cid event.CallerID) {
+	fs.lock.RLock()
+	for _, r := range fs.subRenderables {
+		if t, ok := r.(render.Triggerable); ok {
+			t.SetTriggerID(cid)
+		}
+	}
+	fs.lock.RUnlock()
+}
+
+// isFlag returns whether v is a single bit.
+func isFlag(v int) bool {
+	return v > 0 && v&(v-1) == 0
+}
@@ -0,0 +1,182 @@
+package intswitch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/oakmound/oak/v4/event"
+	"github.com/oakmound/oak/v4/oakerr"
+	"github.com/oakmound/oak/v4/render"
+)
+
+type onceState[K comparable] struct {
+	playing bool
+	// prior is the key the Switch had when the current chain of one-shots
+	// began
+	prior   K
+	current oneShot[K]
+	queue   []oneShot[K]
+	timeout time.Duration
+	// generation tells ending animations and timeouts whether the one-shot
+	// they were waiting on is still playing
+	generation int
+
+	// id is registered for the playing one-shot alone, and given to its
+	// renderable in place of the Switch's trigger ID, so only that renderable
+	// ending its animation ends the one-shot
+	id      event.CallerID
+	binding event.Binding
+	tagged  render.Triggerable
+}
+
+// onceCaller is the entity each one-shot's ID is registered for.
+type onceCaller struct {
+	event.CallerID
+}
+
+type oneShot[K comparable] struct {
+	key  K
+	then *K
+}
+
+// PlayOnce switches to k until its animation ends, then returns to the key the
+// Switch had before. If a one-shot key is already playing, k is queued to play
+// after it, and the Switch returns once every queued key has played.
+//
+// While k plays, its renderable is given an ID of the Switch's own in place of
+// the one given to SetTriggerID, and the one-shot ends when the renderable
+// triggers render.AnimationEnd on that ID, as oak's animations do on
+// event.DefaultBus. For renderables without animations, or to cut long ones
+// short, set a timeout with SetOnceTimeout. Without either, the one-shot could
+// never end, so an error is returned.
+func (c *KeyedSwitch[K]) PlayOnce(k K) error {
+	return c.playOnce(oneShot[K]{key: k})
+}
+
+// PlayOnceThen is PlayOnce, returning to then rather than the prior key. When
+// one-shots are chained, the last one played decides where the Switch returns.
+func (c *KeyedSwitch[K]) PlayOnceThen(k, then K) error {
+	c.lock.RLock()
+	_, ok := c.subRenderables[then]
+	c.lock.RUnlock()
+	if !ok {
+		return oakerr.NotFound{InputName: "then:" + fmt.Sprint(then)}
+	}
+	return c.playOnce(oneShot[K]{key: k, then: &then})
+}
+
+// SetOnceTimeout sets how long one-shot keys play before the Switch moves on
+// if their animation has not ended. A non-positive d waits for the animation
+// indefinitely.
+func (c *KeyedSwitch[K]) SetOnceTimeout(d time.Duration) {
+	c.lock.Lock()
+	c.once.timeout = d
+	c.lock.Unlock()
+}
+
+// PlayingOnce returns whether a one-shot key is playing.
+func (c *KeyedSwitch[K]) PlayingOnce() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.once.playing
+}
+
+// CancelOnce drops any queued one-shot keys and returns from the playing one
+// immediately.
+func (c *KeyedSwitch[K]) CancelOnce() {
+	c.lock.Lock()
+	c.once.queue = nil
+	generation := c.once.generation
+	c.lock.Unlock()
+	c.endOnce(generation)
+}
+
+func (c *KeyedSwitch[K]) playOnce(shot oneShot[K]) error {
+	c.lock.Lock()
+	if _, ok := c.subRenderables[shot.key]; !ok {
+		c.lock.Unlock()
+		return oakerr.NotFound{InputName: "k:" + fmt.Sprint(shot.key)}
+	}
+	if _, ok := c.subRenderables[shot.key].(render.Triggerable); !ok && c.once.timeout <= 0 {
+		c.lock.Unlock()
+		return oakerr.InsufficientInputs{AtLeast: 1, InputName: "animation or timeout"}
+	}
+	if c.once.playing {
+		c.once.queue = append(c.once.queue, shot)
+		c.lock.Unlock()
+		return nil
+	}
+	c.once.playing = true
+	c.once.prior = c.index
+	c.lock.Unlock()
+	c.startOnce(shot)
+	return nil
+}
+
+func (c *KeyedSwitch[K]) startOnce(shot oneShot[K]) {
+	c.lock.Lock()
+	c.once.generation++
+	generation := c.once.generation
+	c.once.current = shot
+	c.releaseOnce()
+	if t, ok := c.subRenderables[shot.key].(render.Triggerable); ok {
+		// a new ID for each one-shot means events its animation triggered
+		// before it ended cannot end the next one
+		c.once.id = event.DefaultCallerMap.Register(&onceCaller{})
+		c.once.tagged = t
+		t.SetTriggerID(c.once.id)
+		c.once.binding = event.DefaultBus.UnsafeBind(render.AnimationEnd.UnsafeEventID, c.once.id, func(event.CallerID, event.Handler, interface{}) event.Response {
+			c.endOnce(generation)
+			return 0
+		})
+	}
+	timeout := c.once.timeout
+	c.lock.Unlock()
+
+	c.Set(shot.key)
+	if timeout > 0 {
+		time.AfterFunc(timeout, func() {
+			c.endOnce(generation)
+		})
+	}
+}
+
+// releaseOnce unbinds and unregisters the playing one-shot's ID, returning its
+// renderable to the Switch's trigger ID. It must be called with the Switch's
+// lock held.
+func (c *KeyedSwitch[K]) releaseOnce() {
+	if c.once.id == 0 {
+		return
+	}
+	c.once.binding.Unbind()
+	event.DefaultCallerMap.RemoveEntity(c.once.id)
+	c.once.tagged.SetTriggerID(c.triggerID)
+	c.once.id = 0
+	c.once.tagged = nil
+}
+
+// endOnce moves on from the one-shot of the given generation, to the next
+// queued one-shot or back out of the chain.
+func (c *KeyedSwitch[K]) endOnce(generation int) {
+	c.lock.Lock()
+	if !c.once.playing || c.once.generation != generation {
+		c.lock.Unlock()
+		return
+	}
+	if len(c.once.queue) != 0 {
+		next := c.once.queue[0]
+		c.once.queue = c.once.queue[1:]
+		c.lock.Unlock()
+		c.startOnce(next)
+		return
+	}
+	target := c.once.prior
+	if c.once.current.then != nil {
+		target = *c.once.current.then
+	}
+	c.once.playing = false
+	c.once.generation++
+	c.releaseOnce()
+	c.lock.Unlock()
+	c.Set(target)
+}
@@ -1,9 +1,9 @@
 package intswitch
 
 import (
+	"fmt"
 	"image"
 	"image/draw"
-	"strconv"
 	"sync"
 
 	"github.com/oakmound/oak/v4/event"
@@ -15,54 +15,114 @@ import (
 
 var _ render.Modifiable = &Switch{}
 
-// The Switch type will display one of a set of modifiable sub-components,
-// keyed on integers. Consider using it with bitflags.
-type Switch struct {
+// A KeyedSwitch will display one of a set of modifiable sub-components, keyed
+// on any comparable type, such as an enum or a struct of several states.
+type KeyedSwitch[K comparable] struct {
 	render.LayeredPoint
-	subRenderables map[int]render.Modifiable
-	Index          int
+	subRenderables map[K]render.Modifiable
+	index          K
 	lock           sync.RWMutex
+
+	// changeHandler and changeID are where SwitchChanged is triggered
+	changeHandler event.Handler
+	changeID      event.CallerID
+
+	transition transitionState
+
+	// triggerID is the ID animations trigger AnimationEnd on, other than a
+	// playing one-shot's
+	triggerID event.CallerID
+	once      onceState[K]
 }
 
+// The Switch type will display one of a set of modifiable sub-components,
+// keyed on integers. To draw several at once with bitflags, use a FlagSwitch.
+type Switch = KeyedSwitch[int]
+
 // New creates a new Switch from a map of values to modifiables
 func New(start int, m map[int]render.Modifiable) *Switch {
-	return &Switch{
+	return NewKeyed(start, m)
+}
+
+// NewKeyed creates a new KeyedSwitch from a map of keys to modifiables
+func NewKeyed[K comparable](start K, m map[K]render.Modifiable) *KeyedSwitch[K] {
+	return &KeyedSwitch[K]{
 		LayeredPoint:   render.NewLayeredPoint(0, 0, 0),
 		subRenderables: m,
-		Index:          start,
+		index:          start,
 		lock:           sync.RWMutex{},
 	}
 }
 
 // Add makes a new entry in the Switch's map. If the key already
 // existed, it will be overwritten and an error will be returned.
-func (c *Switch) Add(k int, v render.Modifiable) (err error) {
+func (c *KeyedSwitch[K]) Add(k K, v render.Modifiable) (err error) {
+	c.lock.Lock()
 	if _, ok := c.subRenderables[k]; ok {
 		err = oakerr.ExistingElement{
 			InputName:   "k",
-			InputType:   "int",
+			InputType:   fmt.Sprintf("%T", k),
 			Overwritten: true,
 		}
 	}
-	c.lock.Lock()
 	c.subRenderables[k] = v
 	c.lock.Unlock()
 	return err
 }
 
-// Set sets the current renderable to the one specified
-func (c *Switch) Set(k int) error {
-	c.lock.RLock()
+// Set sets the current renderable to the one specified, triggering
+// SwitchChanged if the key changed.
+func (c *KeyedSwitch[K]) Set(k K) error {
+	c.lock.Lock()
 	if _, ok := c.subRenderables[k]; !ok {
-		return oakerr.NotFound{InputName: "k:" + strconv.Itoa(k)}
+		c.lock.Unlock()
+		return oakerr.NotFound{InputName: "k:" + fmt.Sprint(k)}
 	}
-	c.lock.RUnlock()
-	c.Index = k
+	old := c.index
+	c.index = k
+	c.beginTransition(old)
+	h, cid := c.changeHandler, c.changeID
+	c.lock.Unlock()
+	triggerChanged(h, cid, old, k)
 	return nil
 }
 
+// CompareAndSet sets the current renderable to the one at new only if the
+// current key is old, reporting whether it did. Like Set, it triggers
+// SwitchChanged if the key changed.
+func (c *KeyedSwitch[K]) CompareAndSet(old, new K) (bool, error) {
+	c.lock.Lock()
+	if _, ok := c.subRenderables[new]; !ok {
+		c.lock.Unlock()
+		return false, oakerr.NotFound{InputName: "new:" + fmt.Sprint(new)}
+	}
+	if c.index != old {
+		c.lock.Unlock()
+		return false, nil
+	}
+	c.index = new
+	c.beginTransition(old)
+	h, cid := c.changeHandler, c.changeID
+	c.lock.Unlock()
+	triggerChanged(h, cid, old, new)
+	return true, nil
+}
+
+// SetChangeTrigger has the Switch trigger SwitchChanged on cid through h
+// whenever its key changes. If h is nil, event.DefaultBus is used, as it is
+// for the AnimationEnd events set up by SetTriggerID.
+func (c *KeyedSwitch[K]) SetChangeTrigger(h event.Handler, cid event.CallerID) {
+	if h == nil {
+		h = event.DefaultBus
+	}
+	c.lock.Lock()
+	c.changeHandler = h
+	c.changeID = cid
+	c.lock.Unlock()
+}
+
 // GetSub returns a keyed Modifiable from this Switch's map
-func (c *Switch) GetSub(s int) render.Modifiable {
+func (c *KeyedSwitch[K]) GetSub(s K) render.Modifiable {
 	c.lock.RLock()
 	m := c.subRenderables[s]
 	c.lock.RUnlock()
@@ -70,12 +130,14 @@ func (c *Switch) GetSub(s int) render.Modifiable {
 }
 
 // Get returns the Switch's current key
-func (c *Switch) Get() int {
-	return c.Index
+func (c *KeyedSwitch[K]) Get() K {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.index
 }
 
 // SetOffsets sets the logical offset for the specified key
-func (c *Switch) SetOffsets(k int, offsets physics.Vector) {
+func (c *KeyedSwitch[K]) SetOffsets(k K, offsets physics.Vector) {
 	c.lock.RLock()
 	if r, ok := c.subRenderables[k]; ok {
 		r.SetPos(offsets.X(), offsets.Y())
@@ -84,31 +146,34 @@ func (c *Switch) SetOffsets(k int, offsets physics.Vector) {
 }
 
 // Copy creates a copy of the Switch
-func (c *Switch) Copy() render.Modifiable {
-	newC := new(Switch)
+func (c *KeyedSwitch[K]) Copy() render.Modifiable {
+	newC := new(KeyedSwitch[K])
 	newC.LayeredPoint = c.LayeredPoint.Copy()
-	newSubRenderables := make(map[int]render.Modifiable)
+	newSubRenderables := make(map[K]render.Modifiable)
 	c.lock.RLock()
 	for k, v := range c.subRenderables {
 		newSubRenderables[k] = v.Copy()
 	}
+	newC.index = c.index
+	newC.transition.fn = c.transition.fn
+	newC.transition.duration = c.transition.duration
+	newC.once.timeout = c.once.timeout
 	c.lock.RUnlock()
 	newC.subRenderables = newSubRenderables
-	newC.Index = c.Index
 	newC.lock = sync.RWMutex{}
 	return newC
 }
 
 //GetRGBA returns the current renderables rgba
-func (c *Switch) GetRGBA() *image.RGBA {
+func (c *KeyedSwitch[K]) GetRGBA() *image.RGBA {
 	c.lock.RLock()
-	rgba := c.subRenderables[c.Index].GetRGBA()
+	rgba := c.subRenderables[c.index].GetRGBA()
 	c.lock.RUnlock()
 	return rgba
 }
 
 // Modify performs the input modifications on all elements of the Switch
-func (c *Switch) Modify(ms ...mod.Mod) render.Modifiable {
+func (c *KeyedSwitch[K]) Modify(ms ...mod.Mod) render.Modifiable {
 	c.lock.RLock()
 	for _, r := range c.subRenderables {
 		r.Modify(ms...)
@@ -118,7 +183,7 @@ func (c *Switch) Modify(ms ...mod.Mod) render.Modifiable {
 }
 
 // Filter filters all elements of the Switch with fs
-func (c *Switch) Filter(fs ...mod.Filter) {
+func (c *KeyedSwitch[K]) Filter(fs ...mod.Filter) {
 	c.lock.RLock()
 	for _, r := range c.subRenderables {
 		r.Filter(fs...)
@@ -127,58 +192,63 @@ func (c *Switch) Filter(fs ...mod.Filter) {
 }
 
 //Draw draws the Switch at an offset from its logical location
-func (c *Switch) Draw(buff draw.Image, xOff float64, yOff float64) {
+func (c *KeyedSwitch[K]) Draw(buff draw.Image, xOff float64, yOff float64) {
 	c.lock.RLock()
-	c.subRenderables[c.Index].Draw(buff, c.X()+xOff, c.Y()+yOff)
+	if c.transition.from != nil {
+		c.drawTransition(buff, c.X()+xOff, c.Y()+yOff)
+		c.lock.RUnlock()
+		return
+	}
+	c.subRenderables[c.index].Draw(buff, c.X()+xOff, c.Y()+yOff)
 	c.lock.RUnlock()
 }
 
 // ShiftPos shifts the Switch's logical position
-func (c *Switch) ShiftPos(x, y float64) {
+func (c *KeyedSwitch[K]) ShiftPos(x, y float64) {
 	c.SetPos(c.X()+x, c.Y()+y)
 }
 
 // GetDims gets the current Renderables dimensions
-func (c *Switch) GetDims() (int, int) {
+func (c *KeyedSwitch[K]) GetDims() (int, int) {
 	c.lock.RLock()
-	w, h := c.subRenderables[c.Index].GetDims()
+	w, h := c.subRenderables[c.index].GetDims()
 	c.lock.RUnlock()
 	return w, h
 }
 
 // Pause stops the current Renderable if possible
-func (c *Switch) Pause() {
+func (c *KeyedSwitch[K]) Pause() {
 	c.lock.RLock()
-	if cp, ok := c.subRenderables[c.Index].(render.CanPause); ok {
+	if cp, ok := c.subRenderables[c.index].(render.CanPause); ok {
 		cp.Pause()
 	}
 	c.lock.RUnlock()
 }
 
 // Unpause tries to unpause the current Renderable if possible
-func (c *Switch) Unpause() {
+func (c *KeyedSwitch[K]) Unpause() {
 	c.lock.RLock()
-	if cp, ok := c.subRenderables[c.Index].(render.CanPause); ok {
+	if cp, ok := c.subRenderables[c.index].(render.CanPause); ok {
 		cp.Unpause()
 	}
 	c.lock.RUnlock()
 }
 
 // IsInterruptable returns whether the current renderable is interruptable
-func (c *Switch) IsInterruptable() bool {
+func (c *KeyedSwitch[K]) IsInterruptable() bool {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
-	if i, ok := c.subRenderables[c.Index].(render.NonInterruptable); ok {
+	if i, ok := c.subRenderables[c.index].(render.NonInterruptable); ok {
 		return i.IsInterruptable()
 	}
 	return true
 }
 
 // IsStatic returns whether the current renderable is static
-func (c *Switch) IsStatic() bool {
+func (c *KeyedSwitch[K]) IsStatic() bool {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
-	if s, ok := c.subRenderables[c.Index].(render.NonStatic); ok {
+	if s, ok := c.subRenderables[c.index].(render.NonStatic); ok {
 		return s.IsStatic()
 	}
 	return true
@@ -188,18 +258,22 @@ func (c *Switch) IsStatic() bool {
 // Todo: standardize this with the other interface Set functions so that it
 // also only acts on the current subRenderable, or the other way around, or
 // somehow offer both options
-func (c *Switch) SetTriggerID(cid event.CID) {
-	c.lock.RLock()
+func (c *KeyedSwitch[K]) SetTriggerID(cid event.CallerID) {
+	c.lock.Lock()
+	c.triggerID = cid
 	for _, r := range c.subRenderables {
 		if t, ok := r.(render.Triggerable); ok {
 			t.SetTriggerID(cid)
 		}
 	}
-	c.lock.RUnlock()
+	if c.once.tagged != nil {
+		c.once.tagged.SetTriggerID(c.once.id)
+	}
+	c.lock.Unlock()
 }
 
 // Revert will revert all parts of this Switch that can be reverted
-func (c *Switch) Revert(mod int) {
+func (c *KeyedSwitch[K]) Revert(mod int) {
 	c.lock.RLock()
 	for _, v := range c.subRenderables {
 		switch t := v.(type) {
@@ -212,7 +286,7 @@ func (c *Switch) Revert(mod int) {
 
 // RevertAll will revert all parts of this Switch that can be reverted, back
 // to their original state.
-func (c *Switch) RevertAll() {
+func (c *KeyedSwitch[K]) RevertAll() {
 	c.lock.RLock()
 	for _, v := range c.subRenderables {
 		switch t := v.(type) {
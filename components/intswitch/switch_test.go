@@ -2,10 +2,17 @@ package intswitch_test
 
 import (
 	"github.com/oakmound/grove/components/intswitch"
+	"github.com/oakmound/oak/v4/alg/floatgeom"
+	"github.com/oakmound/oak/v4/entities"
+	"github.com/oakmound/oak/v4/event"
 	"github.com/oakmound/oak/v4/render"
+	"github.com/oakmound/oak/v4/scene"
 
+	"image"
 	"image/color"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -16,3 +23,427 @@ func TestNew(t *testing.T) {
 		t.Fatal("expected non-nil switch")
 	}
 }
+
+func TestNewKeyed(t *testing.T) {
+	type state struct {
+		dir     string
+		walking bool
+	}
+	idle := render.NewColorBox(1, 1, color.RGBA{255, 0, 0, 255})
+	walk := render.NewColorBox(2, 2, color.RGBA{0, 255, 0, 255})
+	sw := intswitch.NewKeyed(state{"left", false}, map[state]render.Modifiable{
+		{"left", false}: idle,
+		{"left", true}:  walk,
+	})
+	if err := sw.Set(state{"left", true}); err != nil {
+		t.Fatalf("expected set to existing key to succeed: %v", err)
+	}
+	if w, _ := sw.GetDims(); w != 2 {
+		t.Fatalf("expected dims of the walking renderable, got width %d", w)
+	}
+	if err := sw.Add(state{"right", true}, walk.Copy()); err != nil {
+		t.Fatalf("expected add of new key to succeed: %v", err)
+	}
+	if sw.Get() != (state{"left", true}) {
+		t.Fatalf("expected key to be unchanged by add, got %v", sw.Get())
+	}
+	cp := sw.Copy().(*intswitch.KeyedSwitch[state])
+	if cp.Get() != sw.Get() {
+		t.Fatalf("expected copy to keep key %v, got %v", sw.Get(), cp.Get())
+	}
+}
+
+type changeListener struct {
+	cid event.CallerID
+}
+
+func (cl *changeListener) CID() event.CallerID {
+	return cl.cid
+}
+
+func TestSwitch_SetConcurrent(t *testing.T) {
+	cm := event.NewCallerMap()
+	bus := event.NewBus(cm)
+	cl := &changeListener{}
+	cl.cid = cm.Register(cl)
+
+	sw := intswitch.New(0, map[int]render.Modifiable{
+		0: render.NewColorBox(1, 1, color.RGBA{255, 0, 0, 255}),
+		1: render.NewColorBox(2, 2, color.RGBA{0, 255, 0, 255}),
+		2: render.NewColorBox(3, 3, color.RGBA{0, 0, 255, 255}),
+	})
+	sw.SetChangeTrigger(bus, cl.cid)
+
+	changes := make(chan intswitch.SwitchChange[int], 100)
+	b := event.Bind(bus, intswitch.SwitchChanged[int](), cl, func(_ *changeListener, c intswitch.SwitchChange[int]) event.Response {
+		changes <- c
+		return 0
+	})
+	<-b.Bound
+
+	if err := sw.Set(3); err == nil {
+		t.Fatal("expected setting a missing key to fail")
+	}
+	if ok, err := sw.CompareAndSet(0, 3); ok || err == nil {
+		t.Fatal("expected compare and set to a missing key to fail")
+	}
+	if ok, err := sw.CompareAndSet(1, 2); ok || err != nil {
+		t.Fatalf("expected compare and set from a stale key to do nothing, got %v %v", ok, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			sw.Set(i % 3)
+		}(i)
+		go func() {
+			defer wg.Done()
+			k := sw.Get()
+			sw.CompareAndSet(k, (k+1)%3)
+		}()
+		go func() {
+			defer wg.Done()
+			sw.Draw(image.NewRGBA(image.Rect(0, 0, 4, 4)), 0, 0)
+			sw.GetDims()
+		}()
+	}
+	wg.Wait()
+
+	if err := sw.Set(0); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := sw.CompareAndSet(0, 1); !ok || err != nil {
+		t.Fatalf("expected compare and set from the current key to succeed, got %v %v", ok, err)
+	}
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case c := <-changes:
+			if c.Old == c.New {
+				t.Fatalf("expected changes only when the key changed, got %v", c)
+			}
+			if c == (intswitch.SwitchChange[int]{Old: 0, New: 1}) {
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected a change from 0 to 1")
+		}
+	}
+}
+
+func TestSwitch_Transition(t *testing.T) {
+	cm := event.NewCallerMap()
+	bus := event.NewBus(cm)
+	cl := &changeListener{}
+	cl.cid = cm.Register(cl)
+
+	sw := intswitch.New(0, map[int]render.Modifiable{
+		0: render.NewColorBox(4, 4, color.RGBA{255, 0, 0, 255}),
+		1: render.NewColorBox(4, 4, color.RGBA{0, 255, 0, 255}),
+	})
+	sw.SetChangeTrigger(bus, cl.cid)
+	sw.SetTransition(200*time.Millisecond, intswitch.CrossFade)
+
+	done := make(chan struct{}, 1)
+	b := event.Bind(bus, intswitch.TransitionEnd, cl, func(_ *changeListener, _ struct{}) event.Response {
+		done <- struct{}{}
+		return 0
+	})
+	<-b.Bound
+
+	if err := sw.Set(1); err != nil {
+		t.Fatal(err)
+	}
+	if !sw.Transitioning() {
+		t.Fatal("expected switch to be transitioning")
+	}
+	time.Sleep(100 * time.Millisecond)
+	buff := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	sw.Draw(buff, 0, 0)
+	if c := buff.RGBAAt(1, 1); c.R == 0 || c.G == 0 {
+		t.Fatalf("expected both renderables drawn during a cross fade, got %v", c)
+	}
+	for _, tr := range []intswitch.Transition{intswitch.Slide(1, 0), intswitch.ScalePop} {
+		sw.SetTransition(200*time.Millisecond, tr)
+		sw.Set(1 - sw.Get())
+		sw.Draw(buff, 0, 0)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected transition to end")
+	}
+	if sw.Transitioning() {
+		t.Fatal("expected switch to finish transitioning")
+	}
+	buff = image.NewRGBA(image.Rect(0, 0, 4, 4))
+	sw.Draw(buff, 0, 0)
+	if c := buff.RGBAAt(1, 1); c != (color.RGBA{0, 255, 0, 255}) {
+		t.Fatalf("expected only the final renderable drawn, got %v", c)
+	}
+
+	// animations keep playing through a transition, each renderable drawn
+	// at its own offset
+	seq := render.NewSequence(10,
+		render.NewColorBox(4, 4, color.RGBA{255, 0, 0, 255}),
+		render.NewColorBox(4, 4, color.RGBA{0, 0, 255, 255}),
+	)
+	seq.SetPos(4, 0)
+	sw = intswitch.New(0, map[int]render.Modifiable{
+		0: seq,
+		1: render.NewColorBox(4, 4, color.RGBA{0, 255, 0, 255}),
+	})
+	sw.SetTransition(time.Second, intswitch.CrossFade)
+	sw.Set(1)
+	buff = image.NewRGBA(image.Rect(0, 0, 8, 4))
+	sw.Draw(buff, 0, 0)
+	if c := buff.RGBAAt(5, 1); c.R < 200 || c.B != 0 {
+		t.Fatalf("expected the first frame of the sequence at its offset, got %v", c)
+	}
+	time.Sleep(150 * time.Millisecond)
+	buff = image.NewRGBA(image.Rect(0, 0, 8, 4))
+	sw.Draw(buff, 0, 0)
+	if c := buff.RGBAAt(5, 1); c.B < 150 || c.R != 0 || c.G != 0 {
+		t.Fatalf("expected the sequence to advance during the transition, got %v", c)
+	}
+	if c := buff.RGBAAt(1, 1); c.G == 0 || c.R != 0 || c.B != 0 {
+		t.Fatalf("expected the renderable transitioned to at its offset, got %v", c)
+	}
+}
+
+func TestSwitch_PlayOnce(t *testing.T) {
+	newSwitch := func() *intswitch.Switch {
+		return intswitch.New(0, map[int]render.Modifiable{
+			0: render.NewColorBox(1, 1, color.RGBA{255, 0, 0, 255}),
+			1: render.NewColorBox(1, 1, color.RGBA{0, 255, 0, 255}),
+			2: render.NewColorBox(1, 1, color.RGBA{0, 0, 255, 255}),
+		})
+	}
+	waitForReturn := func(sw *intswitch.Switch, draw func()) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for sw.PlayingOnce() {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected one-shots to end, still at key %d", sw.Get())
+			}
+			draw()
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	t.Run("NoEnd", func(t *testing.T) {
+		sw := newSwitch()
+		if err := sw.PlayOnce(1); err == nil {
+			t.Fatal("expected a one-shot which could never end to fail")
+		}
+	})
+	t.Run("Timeout", func(t *testing.T) {
+		cm := event.NewCallerMap()
+		bus := event.NewBus(cm)
+		cl := &changeListener{}
+		cl.cid = cm.Register(cl)
+
+		sw := newSwitch()
+		sw.SetChangeTrigger(bus, cl.cid)
+		sw.SetOnceTimeout(20 * time.Millisecond)
+		changes := make(chan intswitch.SwitchChange[int], 10)
+		b := event.Bind(bus, intswitch.SwitchChanged[int](), cl, func(_ *changeListener, c intswitch.SwitchChange[int]) event.Response {
+			changes <- c
+			return 0
+		})
+		<-b.Bound
+
+		if err := sw.PlayOnce(1); err != nil {
+			t.Fatal(err)
+		}
+		if err := sw.PlayOnce(2); err != nil {
+			t.Fatal(err)
+		}
+		if sw.Get() != 1 {
+			t.Fatalf("expected the first one-shot to play, got key %d", sw.Get())
+		}
+		waitForReturn(sw, func() {})
+		if sw.Get() != 0 {
+			t.Fatalf("expected to return to key 0, got %d", sw.Get())
+		}
+		expected := map[intswitch.SwitchChange[int]]bool{{Old: 0, New: 1}: true, {Old: 1, New: 2}: true, {Old: 2, New: 0}: true}
+		for len(expected) != 0 {
+			select {
+			case c := <-changes:
+				if !expected[c] {
+					t.Fatalf("unexpected change %v", c)
+				}
+				delete(expected, c)
+			case <-time.After(time.Second):
+				t.Fatalf("expected changes %v", expected)
+			}
+		}
+	})
+	t.Run("AnimationEnd", func(t *testing.T) {
+		shared := event.DefaultCallerMap.Register(&changeListener{})
+		seq := render.NewSequence(20,
+			render.NewColorBox(1, 1, color.RGBA{0, 255, 0, 255}),
+			render.NewColorBox(1, 1, color.RGBA{0, 128, 0, 255}),
+		)
+		sw := newSwitch()
+		sw.Add(1, seq)
+		sw.SetTriggerID(shared)
+		if err := sw.PlayOnceThen(1, 2); err != nil {
+			t.Fatal(err)
+		}
+		private := seq.CallerID
+		if private == 0 || private == shared {
+			t.Fatalf("expected the one-shot's renderable to have its own ID, got %v", private)
+		}
+
+		// other animations on the shared ID, like an idle loop, do not end
+		// the one-shot
+		<-event.TriggerForCallerOn(event.DefaultBus, shared, render.AnimationEnd, struct{}{})
+		if !sw.PlayingOnce() || sw.Get() != 1 {
+			t.Fatalf("expected the one-shot to keep playing, got key %d", sw.Get())
+		}
+
+		buff := image.NewRGBA(image.Rect(0, 0, 1, 1))
+		waitForReturn(sw, func() {
+			sw.Draw(buff, 0, 0)
+		})
+		if sw.Get() != 2 {
+			t.Fatalf("expected to continue to key 2, got %d", sw.Get())
+		}
+		if seq.CallerID != shared {
+			t.Fatalf("expected the shared ID restored after the one-shot, got %v", seq.CallerID)
+		}
+		if event.DefaultCallerMap.HasEntity(private) {
+			t.Fatal("expected the one-shot's ID to be released")
+		}
+
+		// each one-shot has a new ID, so late events for an earlier one do
+		// not end it
+		if err := sw.PlayOnce(1); err != nil {
+			t.Fatal(err)
+		}
+		if seq.CallerID == private {
+			t.Fatal("expected a new ID for the next one-shot")
+		}
+		<-event.TriggerForCallerOn(event.DefaultBus, private, render.AnimationEnd, struct{}{})
+		if !sw.PlayingOnce() {
+			t.Fatal("expected an earlier one-shot's event not to end the next")
+		}
+		sw.CancelOnce()
+		if sw.PlayingOnce() || sw.Get() != 2 || seq.CallerID != shared {
+			t.Fatalf("expected cancelling to return to key 2 on the shared ID, got key %d on %v", sw.Get(), seq.CallerID)
+		}
+	})
+}
+
+func TestFlagSwitch(t *testing.T) {
+	const (
+		poisoned = 1 << iota
+		shielded
+		burning
+	)
+	red := color.RGBA{255, 0, 0, 255}
+	green := color.RGBA{0, 255, 0, 255}
+	if _, err := intswitch.NewFlags(0, map[int]render.Modifiable{
+		poisoned | shielded: render.NewColorBox(1, 1, red),
+	}); err == nil {
+		t.Fatal("expected a key of several bits to fail")
+	}
+	fs, err := intswitch.NewFlags(poisoned|shielded, map[int]render.Modifiable{
+		poisoned: render.NewColorBox(2, 2, red),
+		shielded: render.NewColorBox(1, 1, green),
+		burning:  render.NewColorBox(3, 3, color.RGBA{0, 0, 255, 255}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(x, y int) color.RGBA {
+		buff := image.NewRGBA(image.Rect(0, 0, 3, 3))
+		fs.Draw(buff, 0, 0)
+		return buff.RGBAAt(x, y)
+	}
+	if c := at(0, 0); c != green {
+		t.Fatalf("expected higher bits drawn on top, got %v", c)
+	}
+	if c := at(1, 1); c != red {
+		t.Fatalf("expected every set bit drawn, got %v", c)
+	}
+	if c := at(2, 2); c != (color.RGBA{}) {
+		t.Fatalf("expected unset bits not drawn, got %v", c)
+	}
+	if w, h := fs.GetDims(); w != 2 || h != 2 {
+		t.Fatalf("expected dims of the set bits, got %d,%d", w, h)
+	}
+	fs.SetOrder(shielded, poisoned)
+	if c := at(0, 0); c != red {
+		t.Fatalf("expected custom layer order, got %v", c)
+	}
+	fs.Disable(poisoned)
+	fs.Enable(burning)
+	if fs.Get() != shielded|burning || !fs.Has(burning) || fs.Has(poisoned) {
+		t.Fatalf("unexpected flags %b", fs.Get())
+	}
+	if c := at(2, 2); c.B != 255 {
+		t.Fatalf("expected enabled bit drawn, got %v", c)
+	}
+}
+
+func TestDirectionalSwitch(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	right := render.NewColorBox(2, 1, red)
+	right.GetRGBA().Set(1, 0, color.RGBA{})
+	sprites := map[intswitch.Direction]render.Modifiable{
+		intswitch.Right: right,
+		intswitch.Down:  render.NewColorBox(1, 1, red),
+		intswitch.Up:    render.NewColorBox(1, 1, red),
+	}
+	if _, err := intswitch.NewDirectional(intswitch.Right, map[intswitch.Direction]render.Modifiable{
+		intswitch.Right:     right,
+		intswitch.Down:      render.NewColorBox(1, 1, red),
+		intswitch.Up:        render.NewColorBox(1, 1, red),
+		intswitch.DownRight: render.NewColorBox(1, 1, red),
+	}); err == nil {
+		t.Fatal("expected missing diagonals to fail")
+	}
+	ds, err := intswitch.NewDirectional(intswitch.Right, sprites)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := ds.GetSub(intswitch.Left).GetRGBA().RGBAAt(1, 0); c != red {
+		t.Fatalf("expected left generated by flipping right, got %v", c)
+	}
+
+	faces := []struct {
+		delta    floatgeom.Point2
+		expected intswitch.Direction
+	}{
+		{floatgeom.Point2{0, 1}, intswitch.Down},
+		// within the hysteresis of down
+		{floatgeom.Point2{1, 0.9}, intswitch.Down},
+		{floatgeom.Point2{1, 0.5}, intswitch.Right},
+		// idle keeps facing
+		{floatgeom.Point2{0, 0}, intswitch.Right},
+		{floatgeom.Point2{-1, 0}, intswitch.Left},
+		{floatgeom.Point2{-0.1, -1}, intswitch.Up},
+	}
+	for _, f := range faces {
+		if got := ds.Face(f.delta); got != f.expected || ds.Get() != f.expected {
+			t.Fatalf("expected %v to face %v, got %v", f.delta, f.expected, got)
+		}
+	}
+
+	cm := event.NewCallerMap()
+	bus := event.NewBus(cm)
+	ctx := &scene.Context{CallerMap: cm, Handler: bus}
+	e := entities.New(ctx, entities.WithRenderable(ds), entities.WithWithoutCollision(true), entities.WithDrawLayers(nil))
+	e.Delta = floatgeom.Point2{0, 2}
+	b := ds.Follow(ctx, e)
+	<-b.Bound
+	<-event.TriggerOn(bus, event.Enter, event.EnterPayload{})
+	if ds.Get() != intswitch.Down {
+		t.Fatalf("expected to follow the entity's delta down, got %v", ds.Get())
+	}
+}
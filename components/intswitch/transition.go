@@ -0,0 +1,199 @@
+package intswitch
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/oakmound/oak/v4/event"
+	"github.com/oakmound/oak/v4/render"
+	xdraw "golang.org/x/image/draw"
+)
+
+// TransitionEnd is triggered when a switch finishes transitioning to a new
+// key, on the CallerID given to SetChangeTrigger.
+var TransitionEnd = event.RegisterEvent[struct{}]()
+
+// A Transition draws a switch part way through changing from one renderable to
+// another, as progress runs from 0 to 1. dst, from and to are the same size,
+// covering the areas of both renderables, which are each drawn at their own
+// offset within from and to. dst is cleared before each call, and whatever is
+// drawn outside of it is clipped.
+type Transition func(dst, from, to *image.RGBA, progress float64)
+
+type transitionState struct {
+	fn       Transition
+	duration time.Duration
+
+	// from is the renderable being transitioned away from, or nil when no
+	// transition is in progress
+	from  render.Modifiable
+	start time.Time
+	// generation tells the timer ending a transition whether its transition
+	// was replaced by a later one
+	generation int
+
+	// frames are reused to draw and compose each frame of a transition
+	frameLock sync.Mutex
+	fromFrame *image.RGBA
+	toFrame   *image.RGBA
+	frame     *image.RGBA
+}
+
+func (ts *transitionState) progress() float64 {
+	return math.Min(1, float64(time.Since(ts.start))/float64(ts.duration))
+}
+
+// SetTransition has the Switch change between renderables over d, drawing both
+// with t while it does, rather than cutting instantly. Changing keys during a
+// transition starts a new transition from the renderable being transitioned
+// to. A nil t or a non-positive d restores instant cuts.
+func (c *KeyedSwitch[K]) SetTransition(d time.Duration, t Transition) {
+	c.lock.Lock()
+	if d <= 0 {
+		t = nil
+	}
+	c.transition.fn = t
+	c.transition.duration = d
+	c.lock.Unlock()
+}
+
+// Transitioning returns whether the Switch is part way through a transition.
+func (c *KeyedSwitch[K]) Transitioning() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.transition.from != nil
+}
+
+// beginTransition starts a transition from the renderable at old to the
+// current one. It must be called with the Switch's lock held.
+func (c *KeyedSwitch[K]) beginTransition(old K) {
+	ts := &c.transition
+	if ts.fn == nil || old == c.index {
+		return
+	}
+	ts.from = c.subRenderables[old]
+	ts.start = time.Now()
+	ts.generation++
+	generation := ts.generation
+	time.AfterFunc(ts.duration, func() {
+		c.lock.Lock()
+		if ts.generation != generation {
+			c.lock.Unlock()
+			return
+		}
+		ts.from = nil
+		h, cid := c.changeHandler, c.changeID
+		c.lock.Unlock()
+		if h != nil {
+			event.TriggerForCallerOn(h, cid, TransitionEnd, struct{}{})
+		}
+	})
+}
+
+// drawTransition draws the renderables being transitioned between, each at its
+// own offset, into frames covering both, composes the frames with the Switch's
+// transition and draws the result at x, y. Drawing rather than reading the
+// renderables' RGBA keeps animations, like render.Sequences, playing through
+// the transition. It must be called with the Switch's lock held for reading.
+func (c *KeyedSwitch[K]) drawTransition(buff draw.Image, x, y float64) {
+	ts := &c.transition
+	fn, from, to := ts.fn, ts.from, c.subRenderables[c.index]
+	progress := ts.progress()
+	if fn == nil {
+		to.Draw(buff, x, y)
+		return
+	}
+	fromArea, toArea := drawnArea(from), drawnArea(to)
+	area := fromArea.Union(toArea)
+	if area.Empty() {
+		to.Draw(buff, x, y)
+		return
+	}
+
+	ts.frameLock.Lock()
+	defer ts.frameLock.Unlock()
+	ts.fromFrame = clearedFrame(ts.fromFrame, area.Size())
+	ts.toFrame = clearedFrame(ts.toFrame, area.Size())
+	ts.frame = clearedFrame(ts.frame, area.Size())
+	from.Draw(ts.fromFrame, -float64(area.Min.X), -float64(area.Min.Y))
+	to.Draw(ts.toFrame, -float64(area.Min.X), -float64(area.Min.Y))
+	fn(ts.frame, ts.fromFrame, ts.toFrame, progress)
+	pos := image.Pt(int(x), int(y)).Add(area.Min)
+	draw.Draw(buff, ts.frame.Bounds().Add(pos), ts.frame, image.Point{}, draw.Over)
+}
+
+// drawnArea returns where r draws relative to the Switch's position.
+func drawnArea(r render.Modifiable) image.Rectangle {
+	w, h := r.GetDims()
+	at := image.Pt(int(r.X()), int(r.Y()))
+	return image.Rectangle{Min: at, Max: at.Add(image.Pt(w, h))}
+}
+
+// clearedFrame returns frame cleared, or a new frame if frame is not of size.
+func clearedFrame(frame *image.RGBA, size image.Point) *image.RGBA {
+	if frame == nil || frame.Bounds().Size() != size {
+		return image.NewRGBA(image.Rectangle{Max: size})
+	}
+	for i := range frame.Pix {
+		frame.Pix[i] = 0
+	}
+	return frame
+}
+
+// CrossFade fades from out while fading to in.
+func CrossFade(dst, from, to *image.RGBA, progress float64) {
+	drawFaded(dst, from, image.Point{}, 1-progress)
+	drawFaded(dst, to, image.Point{}, progress)
+}
+
+// Slide pushes from out while to slides in after it. dx and dy are the
+// direction to slides in from, in multiples of dst's size: Slide(1, 0) slides
+// to in from the right, pushing from out to the left.
+func Slide(dx, dy float64) Transition {
+	return func(dst, from, to *image.RGBA, progress float64) {
+		// ease in and out
+		p := progress * progress * (3 - 2*progress)
+		b := dst.Bounds()
+		w, h := float64(b.Dx())*dx, float64(b.Dy())*dy
+		fromPos := image.Pt(int(math.Round(-w*p)), int(math.Round(-h*p)))
+		toPos := image.Pt(int(math.Round(w*(1-p))), int(math.Round(h*(1-p))))
+		draw.Draw(dst, from.Bounds().Sub(from.Bounds().Min).Add(fromPos), from, from.Bounds().Min, draw.Over)
+		draw.Draw(dst, to.Bounds().Sub(to.Bounds().Min).Add(toPos), to, to.Bounds().Min, draw.Over)
+	}
+}
+
+// ScalePop fades from out while to grows from its center, briefly
+// overshooting its full size before settling.
+func ScalePop(dst, from, to *image.RGBA, progress float64) {
+	drawFaded(dst, from, image.Point{}, 1-progress)
+
+	// ease out with an overshoot of about ten percent
+	const overshoot = 1.70158
+	p := progress - 1
+	scale := 1 + (overshoot+1)*p*p*p + overshoot*p*p
+	if scale <= 0 {
+		return
+	}
+	tb := to.Bounds()
+	w, h := float64(tb.Dx())*scale, float64(tb.Dy())*scale
+	cx, cy := float64(tb.Dx())/2, float64(tb.Dy())/2
+	dr := image.Rect(
+		int(math.Round(cx-w/2)), int(math.Round(cy-h/2)),
+		int(math.Round(cx+w/2)), int(math.Round(cy+h/2)),
+	)
+	xdraw.ApproxBiLinear.Scale(dst, dr, to, tb, xdraw.Over, nil)
+}
+
+// drawFaded draws src onto dst at pos, with its alpha scaled by alpha.
+func drawFaded(dst, src *image.RGBA, pos image.Point, alpha float64) {
+	if alpha <= 0 {
+		return
+	}
+	mask := image.NewUniform(color.Alpha16{A: uint16(math.Min(1, alpha) * 0xffff)})
+	sb := src.Bounds()
+	draw.DrawMask(dst, sb.Sub(sb.Min).Add(pos), src, sb.Min, mask, image.Point{}, draw.Over)
+}
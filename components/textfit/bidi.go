@@ -0,0 +1,202 @@
+package textfit
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// A visualRune is a character of a line, in the order it is displayed.
+type visualRune struct {
+	r rune
+	// index is the byte offset of the character within the laid out text,
+	// or -1 for characters added during layout.
+	index int
+}
+
+// visualLine returns the characters of text[start:end], one line of a
+// paragraph, from left to right in the order the Unicode bidirectional
+// algorithm displays them, and whether the paragraph reads right to left.
+// Levels are resolved over the whole paragraph, then each line is reordered
+// on its own. Letters are not shaped, and brackets in right to left text are
+// mirrored.
+func visualLine(text string, start, end int) ([]visualRune, bool) {
+	paraStart := strings.LastIndexByte(text[:start], '\n') + 1
+	paraEnd := len(text)
+	if i := strings.IndexByte(text[end:], '\n'); i >= 0 {
+		paraEnd = end + i
+	}
+	para := text[paraStart:paraEnd]
+
+	out := make([]visualRune, 0, end-start)
+	if !hasRightToLeft(para) {
+		for i, r := range text[start:end] {
+			out = append(out, visualRune{r: r, index: start + i})
+		}
+		return out, false
+	}
+
+	var runs []bidiRun
+	for _, run := range paragraphRuns(para) {
+		run.start = maxInt(run.start+paraStart, start)
+		run.end = minInt(run.end+paraStart, end)
+		if run.start < run.end {
+			runs = append(runs, run)
+		}
+	}
+	reorderRuns(runs)
+	for _, run := range runs {
+		if !run.rtl {
+			for i, r := range text[run.start:run.end] {
+				out = append(out, visualRune{r: r, index: run.start + i})
+			}
+			continue
+		}
+		for i := run.end; i > run.start; {
+			r, size := utf8.DecodeLastRuneInString(text[run.start:i])
+			i -= size
+			if p, _ := bidi.LookupRune(r); p.IsBracket() {
+				r, _ = utf8.DecodeRuneInString(bidi.ReverseString(string(r)))
+			}
+			out = append(out, visualRune{r: r, index: i})
+		}
+	}
+	return out, rightToLeft(para)
+}
+
+// rightToLeft reports whether para reads right to left, by its first strongly
+// directional character.
+func rightToLeft(para string) bool {
+	for _, r := range para {
+		p, _ := bidi.LookupRune(r)
+		switch p.Class() {
+		case bidi.L:
+			return false
+		case bidi.R, bidi.AL:
+			return true
+		}
+	}
+	return false
+}
+
+// hasRightToLeft reports whether para holds any right to left characters.
+func hasRightToLeft(para string) bool {
+	for _, r := range para {
+		p, _ := bidi.LookupRune(r)
+		if c := p.Class(); c == bidi.R || c == bidi.AL {
+			return true
+		}
+	}
+	return false
+}
+
+// A bidiRun is a range of a paragraph in one direction, with the embedding
+// level it is displayed at.
+type bidiRun struct {
+	start, end int
+	rtl        bool
+	level      int
+}
+
+// paragraphRuns splits para into runs of one direction, in logical order.
+func paragraphRuns(para string) []bidiRun {
+	var p bidi.Paragraph
+	order, err := func() (bidi.Ordering, error) {
+		if _, err := p.SetString(para); err != nil {
+			return bidi.Ordering{}, err
+		}
+		return p.Order()
+	}()
+	if err != nil || order.NumRuns() == 0 {
+		return []bidiRun{{end: len(para)}}
+	}
+
+	// runs are positioned by rune, so find where each rune starts
+	starts := make([]int, 0, len(para)+1)
+	for i := range para {
+		starts = append(starts, i)
+	}
+	starts = append(starts, len(para))
+
+	runs := make([]bidiRun, order.NumRuns())
+	for i := range runs {
+		run := order.Run(i)
+		first, last := run.Pos()
+		runs[i] = bidiRun{
+			start: starts[first],
+			end:   starts[last+1],
+			rtl:   run.Direction() == bidi.RightToLeft,
+		}
+	}
+
+	// runs only record the direction of their level, so restore levels as
+	// fonthelper.Visual does: right to left runs embed one level, left to
+	// right runs in a right to left paragraph and numbers between right to
+	// left runs two.
+	rtlPara := rightToLeft(para)
+	for i := range runs {
+		switch {
+		case runs[i].rtl:
+			runs[i].level = 1
+		case rtlPara:
+			runs[i].level = 2
+		case i > 0 && i < len(runs)-1 && runs[i-1].rtl && runs[i+1].rtl && isNumeric(para[runs[i].start:runs[i].end]):
+			runs[i].level = 2
+		}
+	}
+	return runs
+}
+
+// reorderRuns reverses each sequence of runs at or above each level, from the
+// highest level down to the lowest odd level (rule L2).
+func reorderRuns(runs []bidiRun) {
+	maxLevel := 0
+	for _, r := range runs {
+		maxLevel = maxInt(maxLevel, r.level)
+	}
+	for level := maxLevel; level >= 1; level-- {
+		for i := 0; i < len(runs); {
+			if runs[i].level < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(runs) && runs[j].level >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				runs[a], runs[b] = runs[b], runs[a]
+			}
+			i = j
+		}
+	}
+}
+
+// isNumeric reports whether s holds only numbers and the characters which
+// separate and qualify them.
+func isNumeric(s string) bool {
+	for _, r := range s {
+		p, _ := bidi.LookupRune(r)
+		switch p.Class() {
+		case bidi.EN, bidi.AN, bidi.ES, bidi.ET, bidi.CS, bidi.NSM, bidi.BN:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
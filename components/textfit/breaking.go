@@ -0,0 +1,154 @@
+package textfit
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// A Hyphenator reports where a word may be hyphenated, for BreakStyleHyphenate.
+type Hyphenator interface {
+	// Hyphenate returns the byte offsets within word, in increasing order,
+	// at which a hyphen may be inserted. Word may include surrounding
+	// punctuation or trailing whitespace.
+	Hyphenate(word string) []int
+}
+
+// HyphenatorFunc adapts a function to the Hyphenator interface.
+type HyphenatorFunc func(word string) []int
+
+// Hyphenate calls hf(word).
+func (hf HyphenatorFunc) Hyphenate(word string) []int {
+	return hf(word)
+}
+
+// A HyphenationDictionary is a Hyphenator which looks words up by their lower
+// case form, mapping them to that form with hyphens at each point the word may
+// be broken, e.g. "hyphenation" -> "hy-phen-a-tion".
+type HyphenationDictionary map[string]string
+
+// Hyphenate returns the hyphenation points of word's dictionary entry, if any.
+func (hd HyphenationDictionary) Hyphenate(word string) []int {
+	notLetter := func(r rune) bool { return !unicode.IsLetter(r) }
+	core := strings.TrimLeftFunc(word, notLetter)
+	lead := len(word) - len(core)
+	core = strings.TrimRightFunc(core, notLetter)
+	pattern, ok := hd[strings.ToLower(core)]
+	if !ok {
+		return nil
+	}
+	var points []int
+	i := 0
+	for _, r := range pattern {
+		if r == '-' {
+			points = append(points, lead+i)
+			continue
+		}
+		if i >= len(core) {
+			break
+		}
+		_, sz := utf8.DecodeRuneInString(core[i:])
+		i += sz
+	}
+	return points
+}
+
+// lineBreaksAround returns the last byte offset in s after which the Unicode
+// line breaking algorithm allows a line break while keeping no more than fit
+// bytes of s, ignoring trailing whitespace, and the first break offset after
+// that. Either offset is -1 if there is no such break.
+func lineBreaksAround(s string, fit int) (last, next int) {
+	var (
+		segment string
+		rest    = s
+		offset  int
+		state   = -1
+	)
+	last = -1
+	for len(rest) > 0 {
+		segment, rest, _, state = uniseg.FirstLineSegmentInString(rest, state)
+		offset += len(segment)
+		if len(trimRightSpace(s[:offset])) > fit {
+			return last, offset
+		}
+		last = offset
+	}
+	return last, -1
+}
+
+// graphemeFloor returns the largest grapheme cluster boundary in s at or
+// before i.
+func graphemeFloor(s string, i int) int {
+	floor := 0
+	gr := uniseg.NewGraphemes(s)
+	for gr.Next() {
+		_, end := gr.Positions()
+		if end > i {
+			break
+		}
+		floor = end
+	}
+	return floor
+}
+
+// firstGrapheme returns the length in bytes of the first grapheme cluster in s.
+func firstGrapheme(s string) int {
+	gr := uniseg.NewGraphemes(s)
+	if !gr.Next() {
+		return 0
+	}
+	_, end := gr.Positions()
+	return end
+}
+
+func trimRightSpace(s string) string {
+	return strings.TrimRightFunc(s, unicode.IsSpace)
+}
+
+// breakLine finds where the line of st starting at byte offset start ends,
+// no later than limit, according to the generator's BreakStyle. It returns the
+// end of the line, the start of the following text, and a suffix to draw after
+// the line.
+func (g *Generator) breakLine(st *sizedText, start, limit, width int) (end, next int, suffix string) {
+	fit := st.fitIndex(start, limit, width)
+	if fit == limit {
+		return fit, fit, ""
+	}
+	sec := st.text[start:limit]
+	if g.BreakStyle != BreakStyleCharacter {
+		// find the last break opportunity that fits, ignoring trailing
+		// whitespace, and the break opportunity after that.
+		last, next := lineBreaksAround(sec, fit-start)
+		if g.BreakStyle == BreakStyleHyphenate && g.Hyphenator != nil && next > 0 {
+			wordStart := last
+			if wordStart < 0 {
+				wordStart = 0
+			}
+			word := trimRightSpace(sec[wordStart:next])
+			points := g.Hyphenator.Hyphenate(word)
+			for j := len(points) - 1; j >= 0; j-- {
+				p := points[j]
+				if p <= 0 || p >= len(word) {
+					continue
+				}
+				end := start + wordStart + p
+				hyphen := st.fonts[st.runIndex(end-1)].measure("-")
+				if (st.measure(start, end) + hyphen).Round() <= width {
+					return end, end, "-"
+				}
+			}
+		}
+		if last > 0 {
+			return start + last, start + last, ""
+		}
+	}
+	// break between characters, or within a word too long for its line
+	bp := graphemeFloor(sec, fit-start)
+	if bp == 0 {
+		// always make progress, even if a single character is too wide
+		bp = firstGrapheme(sec)
+	}
+	return start + bp, start + bp, ""
+}
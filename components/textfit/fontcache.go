@@ -0,0 +1,106 @@
+package textfit
+
+import (
+	"image"
+	"image/color"
+	"sync"
+
+	"github.com/oakmound/oak/v4/render"
+	"golang.org/x/image/math/fixed"
+)
+
+// maxCachedFonts bounds how many regenerated fonts are kept in fontCache
+// before it is emptied.
+const maxCachedFonts = 128
+
+type fontKey struct {
+	font  *render.Font
+	size  int
+	color color.RGBA64
+	// recolor is set if color should replace the font's color
+	recolor bool
+}
+
+// fontCache holds fonts regenerated at specific sizes, as regeneration
+// re-parses the underlying font file.
+var fontCache = struct {
+	sync.Mutex
+	fonts map[fontKey]*sizedFont
+}{
+	fonts: make(map[fontKey]*sizedFont),
+}
+
+// A sizedFont is a font regenerated at a given size which caches the advance
+// of each rune it measures.
+type sizedFont struct {
+	*render.Font
+	size int
+
+	lock     sync.Mutex
+	advances map[rune]fixed.Int26_6
+}
+
+// sizedFontFor returns the cached sizedFont for f at size, regenerating it
+// if it is not cached.
+func sizedFontFor(f *render.Font, size int) *sizedFont {
+	return coloredFontFor(f, size, nil)
+}
+
+// coloredFontFor returns the cached sizedFont for f at size, drawing in c if
+// c is not nil, regenerating it if it is not cached.
+func coloredFontFor(f *render.Font, size int, c color.Color) *sizedFont {
+	key := fontKey{font: f, size: size}
+	if c != nil {
+		key.color = color.RGBA64Model.Convert(c).(color.RGBA64)
+		key.recolor = true
+	}
+	fontCache.Lock()
+	defer fontCache.Unlock()
+	if sf, ok := fontCache.fonts[key]; ok {
+		return sf
+	}
+	regen := func(fg render.FontGenerator) render.FontGenerator {
+		fg.Size = float64(size)
+		if key.recolor {
+			fg.Color = image.NewUniform(key.color)
+		}
+		return fg
+	}
+	font, _ := f.RegenerateWith(regen)
+	// RegenerateWith drops fallbacks, so carry them over at the same size
+	for _, fallback := range f.Fallbacks {
+		if fb, err := fallback.RegenerateWith(regen); err == nil {
+			font.Fallbacks = append(font.Fallbacks, fb)
+		}
+	}
+	sf := &sizedFont{
+		Font:     font,
+		size:     size,
+		advances: make(map[rune]fixed.Int26_6),
+	}
+	if len(fontCache.fonts) >= maxCachedFonts {
+		fontCache.fonts = make(map[fontKey]*sizedFont)
+	}
+	fontCache.fonts[key] = sf
+	return sf
+}
+
+func (sf *sizedFont) advance(r rune) fixed.Int26_6 {
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+	adv, ok := sf.advances[r]
+	if !ok {
+		adv = sf.Font.MeasureString(string(r))
+		sf.advances[r] = adv
+	}
+	return adv
+}
+
+// measure returns the width s would render at, matching Font.MeasureString.
+func (sf *sizedFont) measure(s string) fixed.Int26_6 {
+	var width fixed.Int26_6
+	for _, r := range s {
+		width += sf.advance(r)
+	}
+	return width
+}
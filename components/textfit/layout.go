@@ -0,0 +1,256 @@
+package textfit
+
+import (
+	"math"
+	"unicode"
+
+	"github.com/oakmound/oak/v4/alg/floatgeom"
+	"github.com/oakmound/oak/v4/render"
+	"golang.org/x/image/math/fixed"
+)
+
+// A Layout describes how text was fit: the size it was fit at, and where each
+// line and character was placed. Positions are relative to the top left of the
+// rendered output.
+type Layout struct {
+	// Size is the font size the text was fit at.
+	Size int
+	// Text is the text that was laid out, with any markup removed.
+	Text  string
+	Lines []Line
+
+	texts  []placedText
+	dims   floatgeom.Point2
+	scroll bool
+}
+
+// A Line is one line of laid out text.
+type Line struct {
+	// Text is the text drawn on the line, including any hyphen or ellipsis
+	// that was added to it.
+	Text string
+	// Start and End are the byte offsets of the line within the layout's Text.
+	Start, End int
+	// Bounds spans from the left edge of the line's first glyph to the right
+	// edge of its last, and from the top of the line to its baseline.
+	Bounds   floatgeom.Rect2
+	Baseline float64
+	// Glyphs are ordered from left to right as they are displayed, which is
+	// not their order in Text where right to left text is reordered.
+	Glyphs []Glyph
+}
+
+// A Glyph is one character of laid out text.
+type Glyph struct {
+	Rune rune
+	// Index is the byte offset of the glyph within the layout's Text, or -1
+	// for characters, like hyphens and ellipses, added during layout.
+	Index int
+	// Bounds spans the glyph's advance horizontally, and from the top of its
+	// line to the line's baseline vertically.
+	Bounds floatgeom.Rect2
+	Style  Style
+	Size   int
+}
+
+// GlyphAt returns the glyph whose bounds contain p, if any.
+func (l Layout) GlyphAt(p floatgeom.Point2) (Glyph, bool) {
+	for _, ln := range l.Lines {
+		if p.Y() < ln.Bounds.Min.Y() || p.Y() >= ln.Bounds.Max.Y() {
+			continue
+		}
+		for _, gl := range ln.Glyphs {
+			if p.X() >= gl.Bounds.Min.X() && p.X() < gl.Bounds.Max.X() {
+				return gl, true
+			}
+		}
+	}
+	return Glyph{}, false
+}
+
+// Render draws the laid out text. If the text overflowed with OverflowScroll,
+// the result is a *Scroll; otherwise it is a *render.Sprite.
+func (l Layout) Render() render.Modifiable {
+	sp := l.sprite()
+	if l.scroll {
+		return NewScroll(sp, l.dims)
+	}
+	return sp
+}
+
+// sprite draws all of the laid out text to one sprite.
+func (l Layout) sprite() *render.Sprite {
+	var w, h int
+	for _, pt := range l.texts {
+		if right := int(math.Ceil(pt.X())) + pt.width; right > w {
+			w = right
+		}
+		if bottom := int(math.Ceil(pt.Y())) + pt.height; bottom > h {
+			h = bottom
+		}
+	}
+	sp := render.NewEmptySprite(0, 0, w, h)
+	for _, pt := range l.texts {
+		pt.Draw(sp.GetRGBA(), 0, 0)
+	}
+	return sp
+}
+
+// placedText is a text positioned within rendered output, with the size of
+// the area it draws to.
+type placedText struct {
+	*render.Text
+	width, height int
+}
+
+// place positions lines of st within dims, aligning them vertically with valign.
+func (g *Generator) place(st *sizedText, lines []line, dims floatgeom.Point2, valign VerticalAlign) Layout {
+	l := Layout{
+		Size:  st.size,
+		Text:  st.text,
+		Lines: make([]Line, 0, len(lines)),
+		dims:  g.Dimensions,
+	}
+
+	top := g.RelativePos.Y() + alignOffset(valign, dims.Y(), lines)
+
+	for i, ln := range lines {
+		order, rtl := visualLine(st.text, ln.start, ln.end)
+		// the first line of a paragraph is indented from the side it starts on
+		x := g.RelativePos.X() + ln.left
+		if !rtl {
+			x += ln.indent
+		}
+		y := top + ln.y
+		extra := ln.width - ln.indent - float64((st.measure(ln.start, ln.end) + st.measureSuffix(ln)).Round())
+		gap := 0.0
+		align := g.HorizontalAlign
+		switch {
+		case align == AlignStart && rtl, align == AlignEnd && !rtl:
+			align = AlignRight
+		case align == AlignStart, align == AlignEnd:
+			align = AlignLeft
+		}
+		switch align {
+		case AlignCenter:
+			x += extra / 2
+		case AlignRight:
+			x += extra
+		case AlignJustify:
+			// stretch the spaces between words to span the width
+			if spaces := countSpaces(st.text[ln.start:ln.end]); !ln.last && i != len(lines)-1 && spaces > 0 {
+				gap = extra / float64(spaces)
+			} else if rtl {
+				x += extra
+			}
+		}
+		l.Lines = append(l.Lines, g.placeLine(&l, st, ln, order, rtl, x, y, gap))
+	}
+	return l
+}
+
+// placeLine adds ln's texts to l from left to right, drawing the characters of
+// order, the line in display order, with one text per run and direction. gap
+// is added to the width of each space. If st has letter spacing, each
+// character is placed separately. Any suffix is placed at the end of the line,
+// which is its left side if the line's paragraph is right to left.
+func (g *Generator) placeLine(l *Layout, st *sizedText, ln line, order []visualRune, rtl bool, x, y, gap float64) Line {
+	out := Line{
+		Text:     st.lineText(ln),
+		Start:    ln.start,
+		End:      ln.end,
+		Baseline: y + float64(ln.height),
+	}
+	baseline := out.Baseline
+	draw := func(r int, piece []visualRune) {
+		if len(piece) == 0 {
+			return
+		}
+		runes := make([]rune, len(piece))
+		for i, vr := range piece {
+			runes[i] = vr.r
+		}
+		s := string(runes)
+		font := g.renderFont(st, r)
+		width := font.measure(s).Round()
+		l.texts = append(l.texts, placedText{
+			Text:   font.NewText(s, x, baseline-float64(font.size)),
+			width:  width,
+			height: font.size + font.Face.Metrics().Descent.Ceil(),
+		})
+		var adv fixed.Int26_6
+		for _, vr := range piece {
+			left := x + float64(adv)/64
+			adv += font.advance(vr.r)
+			out.Glyphs = append(out.Glyphs, Glyph{
+				Rune:   vr.r,
+				Index:  vr.index,
+				Bounds: floatgeom.NewRect2(left, y, x+float64(adv)/64, baseline),
+				Style:  st.runs[r].style,
+				Size:   font.size,
+			})
+		}
+		x += float64(width)
+	}
+	suffix := func() {
+		if ln.suffix == "" {
+			return
+		}
+		piece := make([]visualRune, 0, len(ln.suffix))
+		for _, c := range ln.suffix {
+			piece = append(piece, visualRune{r: c, index: -1})
+		}
+		draw(st.suffixRun(ln), piece)
+	}
+
+	if rtl {
+		suffix()
+		if ln.suffix != "" && ln.end > ln.start {
+			x += float64(st.spacing) / 64
+		}
+	}
+	r := -1
+	pieceStart := 0
+	for i, vr := range order {
+		if run := st.runIndex(vr.index); run != r {
+			if r >= 0 {
+				draw(r, order[pieceStart:i])
+				pieceStart = i
+			}
+			r = run
+		}
+		if st.spacing != 0 && i > 0 {
+			draw(r, order[pieceStart:i])
+			pieceStart = i
+			x += float64(st.spacing) / 64
+		}
+		if gap != 0 && unicode.IsSpace(vr.r) {
+			draw(r, order[pieceStart:i])
+			left := x
+			x += float64(st.fonts[r].advance(vr.r).Round()) + gap
+			out.Glyphs = append(out.Glyphs, Glyph{
+				Rune:   vr.r,
+				Index:  vr.index,
+				Bounds: floatgeom.NewRect2(left, y, x, baseline),
+				Style:  st.runs[r].style,
+				Size:   st.fonts[r].size,
+			})
+			pieceStart = i + 1
+		}
+	}
+	if r >= 0 {
+		draw(r, order[pieceStart:])
+	}
+	if !rtl {
+		if ln.suffix != "" && ln.end > ln.start {
+			x += float64(st.spacing) / 64
+		}
+		suffix()
+	}
+	if len(out.Glyphs) > 0 {
+		out.Bounds = floatgeom.NewRect2(out.Glyphs[0].Bounds.Min.X(), y, out.Glyphs[len(out.Glyphs)-1].Bounds.Max.X(), baseline)
+	} else {
+		out.Bounds = floatgeom.NewRect2(x, y, x, baseline)
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package textfit
+
+import (
+	"image/draw"
+	"sync"
+
+	"github.com/oakmound/oak/v4/alg/floatgeom"
+	"github.com/oakmound/oak/v4/render"
+)
+
+var _ render.Renderable = &Live{}
+
+// A Live renderable fits its text like New, but refits it whenever its text or
+// dimensions change. Refitting happens lazily, the next time the Live is drawn
+// or measured, so any number of changes between frames costs one fit.
+type Live struct {
+	render.LayeredPoint
+
+	lock    sync.Mutex
+	gen     Generator
+	fitText string
+	fitDims floatgeom.Point2
+	fitted  render.Modifiable
+	err     error
+	dirty   bool
+}
+
+// NewLive creates a Live renderable from the given options. To have the Live
+// follow a string as it changes, use the StringPtr option.
+func NewLive(options ...Option) *Live {
+	gen := defaultGenerator()
+	for _, opt := range options {
+		opt(gen)
+	}
+	return &Live{
+		LayeredPoint: render.NewLayeredPoint(0, 0, 0),
+		gen:          *gen,
+		dirty:        true,
+	}
+}
+
+// SetText replaces the Live's text, and stops it following any string pointer.
+func (l *Live) SetText(s string) {
+	l.lock.Lock()
+	l.gen.Text = s
+	l.gen.TextPtr = nil
+	l.lock.Unlock()
+}
+
+// SetTextPtr has the Live follow the string behind s.
+func (l *Live) SetTextPtr(s *string) {
+	l.lock.Lock()
+	l.gen.TextPtr = s
+	l.lock.Unlock()
+}
+
+// SetDimensions changes the area the Live's text is fit to.
+func (l *Live) SetDimensions(dims floatgeom.Point2) {
+	l.lock.Lock()
+	l.gen.Dimensions = dims
+	l.lock.Unlock()
+}
+
+// Apply changes the Live's options, such as its font or alignment, and
+// refits its text.
+func (l *Live) Apply(options ...Option) {
+	l.lock.Lock()
+	for _, opt := range options {
+		opt(&l.gen)
+	}
+	l.dirty = true
+	l.lock.Unlock()
+}
+
+// Err returns the error from the last time the Live's text was fit, if any.
+// When fitting fails, the Live keeps drawing the last text that fit.
+func (l *Live) Err() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.refit()
+	return l.err
+}
+
+// refit fits the text again if it has changed. It must be called with the
+// Live's lock held.
+func (l *Live) refit() {
+	text := l.gen.text()
+	if !l.dirty && text == l.fitText && l.gen.Dimensions == l.fitDims {
+		return
+	}
+	l.dirty = false
+	l.fitText = text
+	l.fitDims = l.gen.Dimensions
+	r, err := l.gen.generate()
+	l.err = err
+	if err == nil {
+		l.fitted = r
+	}
+}
+
+// Draw refits the text if needed and draws it at the Live's position.
+func (l *Live) Draw(buff draw.Image, xOff, yOff float64) {
+	l.lock.Lock()
+	l.refit()
+	r := l.fitted
+	l.lock.Unlock()
+	if r != nil {
+		r.Draw(buff, l.X()+xOff, l.Y()+yOff)
+	}
+}
+
+// GetDims refits the text if needed and returns its dimensions.
+func (l *Live) GetDims() (int, int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.refit()
+	if l.fitted == nil {
+		return 1, 1
+	}
+	return l.fitted.GetDims()
+}
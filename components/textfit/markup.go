@@ -0,0 +1,203 @@
+package textfit
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/oakmound/oak/v4/render"
+	"golang.org/x/image/colornames"
+)
+
+// A Style describes how a run of text is drawn. Styles applied by markup are
+// layered over the style of the text that encloses them.
+type Style struct {
+	Bold   bool
+	Italic bool
+	// Color overrides the color of the font if non-nil.
+	Color color.Color
+	// SizeDelta is added to the fitted font size.
+	SizeDelta int
+}
+
+// over returns s layered on top of base.
+func (s Style) over(base Style) Style {
+	base.Bold = base.Bold || s.Bold
+	base.Italic = base.Italic || s.Italic
+	if s.Color != nil {
+		base.Color = s.Color
+	}
+	base.SizeDelta += s.SizeDelta
+	return base
+}
+
+// A FontFamily holds the fonts used to draw marked up text. Any nil font falls
+// back to a similar font in the family, and Regular falls back to the
+// generator's Font.
+type FontFamily struct {
+	Regular    *render.Font
+	Bold       *render.Font
+	Italic     *render.Font
+	BoldItalic *render.Font
+}
+
+func (ff FontFamily) pick(s Style, regular *render.Font) *render.Font {
+	if ff.Regular != nil {
+		regular = ff.Regular
+	}
+	candidates := []*render.Font{regular}
+	switch {
+	case s.Bold && s.Italic:
+		candidates = []*render.Font{ff.BoldItalic, ff.Bold, ff.Italic, regular}
+	case s.Bold:
+		candidates = []*render.Font{ff.Bold, regular}
+	case s.Italic:
+		candidates = []*render.Font{ff.Italic, regular}
+	}
+	for _, f := range candidates {
+		if f != nil {
+			return f
+		}
+	}
+	return regular
+}
+
+// A run is a section of text drawn in one style, ending at byte offset end
+// of its text and starting where the previous run ended.
+type run struct {
+	end   int
+	style Style
+}
+
+// styledText is text along with the styles it is drawn in.
+type styledText struct {
+	text string
+	runs []run
+}
+
+func plainText(s string) styledText {
+	return styledText{
+		text: s,
+		runs: []run{{end: len(s)}},
+	}
+}
+
+type openTag struct {
+	name  string
+	style Style
+}
+
+// parseMarkup parses s as marked up text, as described by WithMarkup, with
+// the named styles in styles.
+func parseMarkup(s string, styles map[string]Style) styledText {
+	var (
+		sb    strings.Builder
+		runs  []run
+		stack []openTag
+	)
+	// flush ends the run in progress before the style changes
+	flush := func() {
+		start := 0
+		if len(runs) > 0 {
+			start = runs[len(runs)-1].end
+		}
+		if sb.Len() == start {
+			return
+		}
+		st := Style{}
+		for _, t := range stack {
+			st = t.style.over(st)
+		}
+		runs = append(runs, run{end: sb.Len(), style: st})
+	}
+	for len(s) > 0 {
+		open := strings.IndexByte(s, '<')
+		if open < 0 {
+			sb.WriteString(s)
+			break
+		}
+		sb.WriteString(s[:open])
+		s = s[open:]
+		close := strings.IndexByte(s, '>')
+		if close < 0 {
+			sb.WriteString(s)
+			break
+		}
+		tag := s[1:close]
+		if strings.IndexByte(tag, '<') >= 0 {
+			// a lone '<' precedes this tag
+			sb.WriteByte('<')
+			s = s[1:]
+			continue
+		}
+		if strings.HasPrefix(tag, "/") {
+			found := -1
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].name == tag[1:] {
+					found = i
+					break
+				}
+			}
+			if found >= 0 {
+				flush()
+				stack = append(stack[:found], stack[found+1:]...)
+			} else {
+				sb.WriteString(s[:close+1])
+			}
+		} else if name, style, ok := parseTag(tag, styles); ok {
+			flush()
+			stack = append(stack, openTag{name: name, style: style})
+		} else {
+			sb.WriteString(s[:close+1])
+		}
+		s = s[close+1:]
+	}
+	flush()
+	if len(runs) == 0 {
+		runs = append(runs, run{end: sb.Len()})
+	}
+	return styledText{text: sb.String(), runs: runs}
+}
+
+func parseTag(tag string, styles map[string]Style) (name string, style Style, ok bool) {
+	name, value, hasValue := strings.Cut(tag, "=")
+	switch name {
+	case "b":
+		return name, Style{Bold: true}, !hasValue
+	case "i":
+		return name, Style{Italic: true}, !hasValue
+	case "color":
+		c, err := parseColor(value)
+		return name, Style{Color: c}, err == nil
+	case "size":
+		if !strings.HasPrefix(value, "+") && !strings.HasPrefix(value, "-") {
+			return name, style, false
+		}
+		delta, err := strconv.Atoi(value)
+		return name, Style{SizeDelta: delta}, err == nil
+	}
+	style, ok = styles[name]
+	return name, style, ok && !hasValue
+}
+
+func parseColor(s string) (color.Color, error) {
+	if c, ok := colornames.Map[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+	if !strings.HasPrefix(s, "#") {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+	s = s[1:]
+	c := color.RGBA{A: 255}
+	var err error
+	switch len(s) {
+	case 6:
+		_, err = fmt.Sscanf(s, "%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 8:
+		_, err = fmt.Sscanf(s, "%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	default:
+		err = fmt.Errorf("invalid color length %v", len(s))
+	}
+	return c, err
+}
@@ -0,0 +1,70 @@
+package textfit
+
+import (
+	"math"
+
+	"github.com/oakmound/oak/v4/alg/floatgeom"
+	"github.com/oakmound/oak/v4/shape"
+)
+
+// A LineSpan returns the horizontal span, from left to right, that a line of
+// text covering the rows from top to bottom may be drawn within. Positions are
+// relative to the top left of the area text is fit to, inside any inset.
+type LineSpan func(top, bottom float64) (left, right float64)
+
+// ShapeSpan returns a LineSpan that keeps lines inside sh, with sh sized to
+// dims. Each line spans from the rightmost left edge to the leftmost right edge
+// of the rows it covers. Rows outside of dims do not narrow lines. Negative
+// dimensions, as left by an inset larger than the area, are treated as 0.
+func ShapeSpan(sh shape.Shape, dims floatgeom.Point2) LineSpan {
+	dims = floatgeom.Point2{math.Max(0, dims.X()), math.Max(0, dims.Y())}
+	w, h := int(dims.X()), int(dims.Y())
+	// rows holds the left and right edges, exclusive of the right, of each
+	// row of sh; rows sh does not cover are empty
+	rows := make([][2]int, h)
+	for y := range rows {
+		left, right := -1, 0
+		for x := 0; x < w; x++ {
+			if sh.In(x, y, w, h) {
+				if left < 0 {
+					left = x
+				}
+				right = x + 1
+			}
+		}
+		if left < 0 {
+			left = 0
+		}
+		rows[y] = [2]int{left, right}
+	}
+	return func(top, bottom float64) (float64, float64) {
+		left, right := 0.0, dims.X()
+		for y := int(math.Floor(top)); y < int(math.Ceil(bottom)); y++ {
+			if y < 0 || y >= h {
+				continue
+			}
+			left = math.Max(left, float64(rows[y][0]))
+			right = math.Min(right, float64(rows[y][1]))
+		}
+		return left, right
+	}
+}
+
+// lineSpan returns the LineSpan lines are fit within in an area of dims, or
+// nil if lines span the full width.
+func (g *Generator) lineSpan(dims floatgeom.Point2) LineSpan {
+	if g.Shape != nil {
+		return ShapeSpan(g.Shape, dims)
+	}
+	return g.LineSpan
+}
+
+// spanAt returns where a line covering the rows from top to bottom may be
+// drawn within an area width wide.
+func spanAt(span LineSpan, top, bottom, width float64) (left, right float64) {
+	if span == nil {
+		return 0, width
+	}
+	left, right = span(top, bottom)
+	return math.Max(left, 0), math.Min(right, width)
+}
@@ -0,0 +1,107 @@
+package textfit
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/oakmound/oak/v4/alg/floatgeom"
+	"github.com/oakmound/oak/v4/render"
+	"github.com/oakmound/oak/v4/render/mod"
+)
+
+var _ render.Modifiable = &Scroll{}
+
+// A Scroll displays a fixed size window into a taller renderable, such as text
+// which overflowed its bounds. The window starts at the top of the content and
+// can be moved with ScrollTo and ScrollBy.
+type Scroll struct {
+	render.LayeredPoint
+	content *render.Sprite
+	w, h    int
+	offset  int
+}
+
+// NewScroll creates a Scroll showing a window of the given dimensions into content.
+func NewScroll(content *render.Sprite, dims floatgeom.Point2) *Scroll {
+	return &Scroll{
+		LayeredPoint: render.NewLayeredPoint(content.X(), content.Y(), 0),
+		content:      content,
+		w:            int(dims.X()),
+		h:            int(dims.Y()),
+	}
+}
+
+// ScrollTo sets how far down the content the window begins, clamped so that
+// the window stays within the content.
+func (s *Scroll) ScrollTo(y int) {
+	if max := s.MaxOffset(); y > max {
+		y = max
+	}
+	if y < 0 {
+		y = 0
+	}
+	s.offset = y
+}
+
+// ScrollBy shifts the window down the content by dy, or up if dy is negative.
+func (s *Scroll) ScrollBy(dy int) {
+	s.ScrollTo(s.offset + dy)
+}
+
+// Offset returns how far down the content the window begins.
+func (s *Scroll) Offset() int {
+	return s.offset
+}
+
+// MaxOffset returns the furthest down the content the window can begin.
+func (s *Scroll) MaxOffset() int {
+	_, h := s.content.GetDims()
+	if h <= s.h {
+		return 0
+	}
+	return h - s.h
+}
+
+func (s *Scroll) window() image.Rectangle {
+	return image.Rect(0, s.offset, s.w, s.offset+s.h).Intersect(s.content.GetRGBA().Bounds())
+}
+
+// Draw draws the visible window of the content.
+func (s *Scroll) Draw(buff draw.Image, xOff, yOff float64) {
+	win := s.window()
+	dst := image.Rect(int(s.X()+xOff), int(s.Y()+yOff), int(s.X()+xOff)+win.Dx(), int(s.Y()+yOff)+win.Dy())
+	draw.Draw(buff, dst, s.content.GetRGBA(), win.Min, draw.Over)
+}
+
+// GetDims returns the dimensions of the window.
+func (s *Scroll) GetDims() (int, int) {
+	return s.w, s.h
+}
+
+// GetRGBA returns a copy of the visible window of the content.
+func (s *Scroll) GetRGBA() *image.RGBA {
+	win := s.window()
+	rgba := image.NewRGBA(image.Rect(0, 0, s.w, s.h))
+	draw.Draw(rgba, win.Sub(win.Min), s.content.GetRGBA(), win.Min, draw.Src)
+	return rgba
+}
+
+// Modify applies the mods to the whole content.
+func (s *Scroll) Modify(ms ...mod.Mod) render.Modifiable {
+	s.content.Modify(ms...)
+	s.ScrollTo(s.offset)
+	return s
+}
+
+// Filter applies the filters to the whole content.
+func (s *Scroll) Filter(fs ...mod.Filter) {
+	s.content.Filter(fs...)
+}
+
+// Copy returns a copy of this Scroll.
+func (s *Scroll) Copy() render.Modifiable {
+	s2 := *s
+	s2.LayeredPoint = s.LayeredPoint.Copy()
+	s2.content = s.content.Copy().(*render.Sprite)
+	return &s2
+}
@@ -0,0 +1,134 @@
+package textfit
+
+import (
+	"sort"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// sizedText is styled text with the fonts to measure each of its runs at a
+// particular fitted size.
+type sizedText struct {
+	styledText
+	size  int
+	fonts []*sizedFont
+	// spacing is added between characters
+	spacing fixed.Int26_6
+}
+
+func (g *Generator) fontFor(s Style, size int) (font *sizedFont, runSize int) {
+	runSize = size + s.SizeDelta
+	if runSize < 1 {
+		runSize = 1
+	}
+	return sizedFontFor(g.Family.pick(s, g.Font), runSize), runSize
+}
+
+func (g *Generator) sizeText(text styledText, size int) *sizedText {
+	st := &sizedText{
+		styledText: text,
+		size:       size,
+		fonts:      make([]*sizedFont, len(text.runs)),
+		spacing:    fixed.Int26_6(g.LetterSpacing * float64(size) * 64),
+	}
+	for i, r := range text.runs {
+		st.fonts[i], _ = g.fontFor(r.style, size)
+	}
+	return st
+}
+
+// renderFont returns the font to draw the given run of st with.
+func (g *Generator) renderFont(st *sizedText, run int) *sizedFont {
+	style := st.runs[run].style
+	if style.Color == nil {
+		return st.fonts[run]
+	}
+	_, runSize := g.fontFor(style, st.size)
+	return coloredFontFor(g.Family.pick(style, g.Font), runSize, style.Color)
+}
+
+// runIndex returns the index of the run containing byte offset i.
+func (st *sizedText) runIndex(i int) int {
+	r := sort.Search(len(st.runs), func(r int) bool {
+		return st.runs[r].end > i
+	})
+	if r == len(st.runs) {
+		r--
+	}
+	return r
+}
+
+// measure returns the width of the text between byte offsets start and end.
+func (st *sizedText) measure(start, end int) fixed.Int26_6 {
+	var width fixed.Int26_6
+	r := st.runIndex(start)
+	for i, c := range st.text[start:end] {
+		for start+i >= st.runs[r].end {
+			r++
+		}
+		if i > 0 {
+			width += st.spacing
+		}
+		width += st.fonts[r].advance(c)
+	}
+	return width
+}
+
+// suffixRun returns the run a line's suffix is drawn in.
+func (st *sizedText) suffixRun(ln line) int {
+	if ln.end > ln.start {
+		return st.runIndex(ln.end - 1)
+	}
+	return st.runIndex(ln.start)
+}
+
+func (st *sizedText) measureSuffix(ln line) fixed.Int26_6 {
+	if ln.suffix == "" {
+		return 0
+	}
+	width := st.fonts[st.suffixRun(ln)].measure(ln.suffix)
+	if ln.end > ln.start {
+		width += st.spacing
+	}
+	return width
+}
+
+// fitIndex returns the byte offset of the first rune between start and limit
+// which does not fit within width, or limit if all of that text fits.
+func (st *sizedText) fitIndex(start, limit, width int) int {
+	var w fixed.Int26_6
+	r := st.runIndex(start)
+	for i, c := range st.text[start:limit] {
+		for start+i >= st.runs[r].end {
+			r++
+		}
+		if i > 0 {
+			w += st.spacing
+		}
+		w += st.fonts[r].advance(c)
+		if w.Round() > width {
+			return start + i
+		}
+	}
+	return limit
+}
+
+// lineHeight returns the size of the largest font used between byte offsets
+// start and end.
+func (st *sizedText) lineHeight(start, end int) int {
+	height := 0
+	for r := st.runIndex(start); r < len(st.runs); r++ {
+		if h := st.fonts[r].size; h > height {
+			height = h
+		}
+		if st.runs[r].end >= end {
+			break
+		}
+	}
+	return height
+}
+
+// lineText returns the text drawn on ln, including its suffix.
+func (st *sizedText) lineText(ln line) string {
+	return st.text[ln.start:ln.end] + ln.suffix
+}
@@ -2,110 +2,383 @@ package textfit
 
 import (
 	"fmt"
-	"sort"
+	"math"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/oakmound/oak/v4/alg/floatgeom"
 	"github.com/oakmound/oak/v4/render"
+	"github.com/oakmound/oak/v4/shape"
 )
 
 type Generator struct {
-	Text       string
+	Text string
+	// TextPtr, if set, is used in place of Text.
+	TextPtr    *string
 	Font       *render.Font
 	Dimensions floatgeom.Point2
 	MinSize    int
 	MaxSize    int
 	BreakStyle
 	RelativePos floatgeom.Point2
-	// overflow style - clip / newlines on word / newlines on character / trail off (...)
+	Overflow
+	HorizontalAlign
+	VerticalAlign
+	// Hyphenator is used by BreakStyleHyphenate to find where words may be split.
+	Hyphenator
+	// Ellipsis is appended to the last visible line by OverflowEllipsis.
+	Ellipsis string
+	// LineHeight multiplies the distance from the top of one line to the next.
+	LineHeight float64
+	// ParagraphSpacing, in multiples of the font size, is added between
+	// paragraphs, which are separated by blank lines.
+	ParagraphSpacing float64
+	// FirstLineIndent, in multiples of the font size, indents the first line
+	// of each paragraph.
+	FirstLineIndent float64
+	// LetterSpacing, in multiples of the font size, is added between characters.
+	LetterSpacing float64
+	// Markup enables parsing Text as marked up text, drawn with the fonts in
+	// Family and the named styles in Styles. See WithMarkup.
+	Markup bool
+	Family FontFamily
+	Styles map[string]Style
+	// Shape, if set, is the region within the dimensions text is fit to.
+	Shape shape.Shape
+	// LineSpan, if set and Shape is not, gives the width available to each
+	// line of text.
+	LineSpan LineSpan
 	// padding
 }
 
 type BreakStyle byte
 
 const (
+	// BreakStyleCharacter breaks lines between any two characters.
 	BreakStyleCharacter BreakStyle = iota
+	// BreakStyleWord breaks lines where the Unicode line breaking algorithm
+	// allows, e.g. after spaces or between ideographs, falling back to
+	// breaking between characters for words longer than a line.
 	BreakStyleWord
+	// BreakStyleHyphenate breaks lines as BreakStyleWord does, but will also
+	// hyphenate words at the points the generator's Hyphenator allows.
+	BreakStyleHyphenate
 )
 
+// Overflow controls what happens when text does not fit within the generator's
+// dimensions even at MinSize.
+type Overflow byte
+
+const (
+	// OverflowError returns an error.
+	OverflowError Overflow = iota
+	// OverflowClip drops every line after the last one that fits.
+	OverflowClip
+	// OverflowEllipsis drops every line after the last one that fits, and
+	// truncates that line to end with the generator's Ellipsis.
+	OverflowEllipsis
+	// OverflowScroll renders every line into a *Scroll, which displays a
+	// window of the text the size of the generator's dimensions.
+	OverflowScroll
+)
+
+// HorizontalAlign controls where each line is placed within the generator's width.
+type HorizontalAlign byte
+
+const (
+	AlignLeft HorizontalAlign = iota
+	AlignCenter
+	AlignRight
+	// AlignJustify stretches the spaces between words so that every line
+	// but the last spans the full width. The last line is aligned to the
+	// side its paragraph starts on.
+	AlignJustify
+	// AlignStart and AlignEnd place each line on the side its paragraph
+	// starts or ends on: left and right for left to right paragraphs, and
+	// the reverse for right to left paragraphs.
+	AlignStart
+	AlignEnd
+)
+
+// VerticalAlign controls where the block of lines is placed within the
+// generator's height.
+type VerticalAlign byte
+
+const (
+	AlignTop VerticalAlign = iota
+	AlignMiddle
+	AlignBottom
+)
+
+func (g *Generator) text() string {
+	if g.TextPtr != nil {
+		return *g.TextPtr
+	}
+	return g.Text
+}
+
+func (g *Generator) styledText() styledText {
+	text := strings.ReplaceAll(g.text(), "\r\n", "\n")
+	if g.Markup {
+		return parseMarkup(text, g.Styles)
+	}
+	return plainText(text)
+}
+
 func (g *Generator) generate() (render.Modifiable, error) {
-	size := g.MaxSize
+	l, err := g.layout()
+	if err != nil {
+		return nil, err
+	}
+	return l.Render(), nil
+}
+
+func (g *Generator) layout() (Layout, error) {
 	dims := g.Dimensions.Sub(g.RelativePos.MulConst(2))
+	// an inset larger than the dimensions leaves no room, where a negative
+	// height would otherwise let lines wrap without limit
+	dims = floatgeom.Point2{math.Max(0, dims.X()), math.Max(0, dims.Y())}
+	text := g.styledText()
+	span := g.lineSpan(dims)
 
-	var sections []string
-ONESECTION:
-	for {
-		font, _ := g.Font.RegenerateWith(func(f render.FontGenerator) render.FontGenerator {
-			f.Size = float64(size)
-			return f
-		})
-		sections = []string{g.Text}
-	HEIGHTCHECK:
-		for {
-			remainingHeight := dims.Y() - float64(size*len(sections))
-			if remainingHeight < 0 {
-				size--
-				if size < g.MinSize {
-					return nil, fmt.Errorf("size fell below minSize; decrease min size, decrease text ct, or increase bounds")
-				}
-				continue ONESECTION
+	// Larger sizes never fit more text, so binary search for the largest
+	// size that fits.
+	var (
+		best  *sizedText
+		lines []line
+	)
+	lo, hi := g.MinSize, g.MaxSize
+	if lo < 1 {
+		lo = 1
+	}
+	for lo <= hi {
+		size := lo + (hi-lo)/2
+		st := g.sizeText(text, size)
+		if lns, ok := g.wrapSpan(st, dims.X(), dims.Y(), span, 0); ok {
+			best, lines = st, lns
+			lo = size + 1
+		} else {
+			hi = size - 1
+		}
+	}
+	if best != nil {
+		if span == nil {
+			return g.place(best, lines, dims, g.VerticalAlign), nil
+		}
+		// The width of each line depends on where it is, so rather than
+		// moving the lines into place, wrap them again where they would be
+		// moved to.
+		if top := alignOffset(g.VerticalAlign, dims.Y(), lines); top > 0 {
+			if lns, ok := g.wrapSpan(best, dims.X(), dims.Y(), span, top); ok {
+				lines = lns
 			}
-			for i, sec := range sections {
-				sec = strings.TrimSpace(sec)
-				length := font.MeasureString(sec).Round()
-				if length > int(dims.X()) {
-					breakPoint := sort.Search(len(sec), func(i int) bool {
-						// returns the smallest index for which i is true,
-						// so we want to return true if we are above our goal width,
-						// and then subtract 1.
-						length := font.MeasureString(sec[:i]).Round()
-						return length > int(dims.X())
-					})
-					breakPoint--
-					if g.BreakStyle == BreakStyleWord {
-						// walk back to the last space
-						for breakPoint > 1 && sec[breakPoint] != ' ' {
-							breakPoint--
-						}
-					}
+		}
+		return g.place(best, lines, dims, AlignTop), nil
+	}
+
+	if g.Overflow == OverflowError || g.MinSize <= 0 {
+		return Layout{}, fmt.Errorf("size fell below minSize; decrease min size, decrease text ct, or increase bounds")
+	}
+	st := g.sizeText(text, g.MinSize)
+	lines, _ = g.wrapSpan(st, dims.X(), -1, span, 0)
+	switch g.Overflow {
+	case OverflowClip, OverflowEllipsis:
+		visible := 0
+		for _, ln := range lines {
+			if ln.bottom() > dims.Y() {
+				break
+			}
+			visible++
+		}
+		if visible < len(lines) {
+			lines = lines[:visible]
+			if g.Overflow == OverflowEllipsis && visible > 0 {
+				lines[visible-1] = g.ellipsize(st, lines[visible-1])
+			}
+		}
+	case OverflowScroll:
+		l := g.place(st, lines, dims, g.VerticalAlign)
+		l.scroll = true
+		return l, nil
+	}
+	return g.place(st, lines, dims, g.VerticalAlign), nil
+}
 
-					sections[i] = sec[:breakPoint]
-					sections = append(sections[:i+1], append(
-						[]string{sec[breakPoint:]}, sections[i+1:]...)...)
+// A line is a range of a sizedText's text, with any trailing whitespace
+// excluded, to draw on one line.
+type line struct {
+	start, end int
+	// suffix is drawn after the line's text, as a hyphen or ellipsis.
+	suffix string
+	// y is the top of the line, relative to the top of the area it was
+	// wrapped into.
+	y      float64
+	height int
+	// left and width are the span the line was wrapped into, including its
+	// indent.
+	left, width float64
+	indent      float64
+	// last is set if the line ends at a newline or the end of the text.
+	last bool
+}
+
+func (ln line) bottom() float64 {
+	return ln.y + float64(ln.height)
+}
 
-					continue HEIGHTCHECK
+// wrapSpan breaks the generator's text into lines no wider than width. If the
+// lines would be taller than maxHeight, wrapSpan gives up and returns false. A
+// negative maxHeight places no limit on the height of the lines.
+//
+// Each newline in the text starts a new line, and blank lines separate
+// paragraphs. Each line is narrowed to the part of width span allows, with
+// the first line starting top pixels down, and lines are moved down past rows
+// too narrow to fit any text. A nil span allows the full width.
+func (g *Generator) wrapSpan(st *sizedText, width, maxHeight float64, span LineSpan, top float64) ([]line, bool) {
+	var lines []line
+	lineHeight := g.LineHeight
+	if lineHeight == 0 {
+		lineHeight = 1
+	}
+	size := float64(st.size)
+	y := top
+	pos := 0
+	newParagraph := true
+	for {
+		hardEnd := len(st.text)
+		if i := strings.IndexByte(st.text[pos:], '\n'); i >= 0 {
+			hardEnd = pos + i
+		}
+		start := skipSpace(st.text[:hardEnd], pos)
+		if start == hardEnd {
+			// a blank line ends the paragraph
+			newParagraph = true
+		}
+		if start != hardEnd && newParagraph && len(lines) > 0 {
+			y += g.ParagraphSpacing * size
+		}
+		for start < hardEnd {
+			indent := 0.0
+			if newParagraph {
+				indent = g.FirstLineIndent * size
+				newParagraph = false
+			}
+			left, right := spanAt(span, y, y+size, width)
+			if span != nil {
+				// skip rows where not even one character fits, giving up
+				// after a few lines' worth so open regions cannot loop forever
+				for skipped := 0.0; skipped < 4*size; skipped++ {
+					if st.fitIndex(start, hardEnd, int(right-left-indent)) > start {
+						break
+					}
+					if maxHeight >= 0 && y+size > maxHeight {
+						return lines, false
+					}
+					y++
+					left, right = spanAt(span, y, y+size, width)
+				}
+			}
+			ln := g.wrapLine(st, start, hardEnd, left, right, y, indent)
+			if span != nil && ln.height > st.size {
+				// larger text in the line covers more rows, which may be narrower
+				if l, r := spanAt(span, y, y+float64(ln.height), width); l > left || r < right {
+					ln = g.wrapLine(st, start, hardEnd, l, r, y, indent)
 				}
 			}
-			break ONESECTION
+			start = ln.next
+			ln.last = start == hardEnd
+			lines = append(lines, ln.line)
+			if maxHeight >= 0 && ln.bottom() > maxHeight {
+				return lines, false
+			}
+			y += float64(ln.height) * lineHeight
+		}
+		if hardEnd == len(st.text) {
+			return lines, true
 		}
+		pos = hardEnd + 1
 	}
+}
 
-	comp := render.NewCompositeR()
+// wrappedLine is a line along with where the text after it starts.
+type wrappedLine struct {
+	line
+	next int
+}
 
-	font, _ := g.Font.RegenerateWith(func(f render.FontGenerator) render.FontGenerator {
-		f.Size = float64(size)
-		return f
-	})
-	x := g.RelativePos.X()
-	y := g.RelativePos.Y()
+// wrapLine breaks one line of text from start, ending no later than limit,
+// into the span from left to right.
+func (g *Generator) wrapLine(st *sizedText, start, limit int, left, right, y, indent float64) wrappedLine {
+	end, next, suffix := g.breakLine(st, start, limit, int(right-left-indent))
+	ln := line{
+		start:  start,
+		end:    start + len(trimRightSpace(st.text[start:end])),
+		suffix: suffix,
+		y:      y,
+		left:   left,
+		width:  right - left,
+		indent: indent,
+	}
+	ln.height = st.lineHeight(ln.start, ln.end)
+	return wrappedLine{line: ln, next: skipSpace(st.text[:limit], next)}
+}
 
-	for _, sec := range sections {
-		sec = strings.TrimSpace(sec)
-		t := font.NewText(sec, x, y)
-		y += float64(size)
-		comp.Append(t.ToSprite())
+// alignOffset returns how far down lines should be moved to be aligned within
+// height.
+func alignOffset(a VerticalAlign, height float64, lines []line) float64 {
+	if len(lines) == 0 {
+		return 0
+	}
+	blockHeight := lines[len(lines)-1].bottom() - lines[0].y
+	if blockHeight >= height {
+		return 0
+	}
+	switch a {
+	case AlignMiddle:
+		return (height - blockHeight) / 2
+	case AlignBottom:
+		return height - blockHeight
 	}
+	return 0
+}
+
+// ellipsize trims characters from the end of ln until it fits within its
+// width with the generator's Ellipsis appended.
+func (g *Generator) ellipsize(st *sizedText, ln line) line {
+	width := ln.width - ln.indent
+	ln.suffix = g.Ellipsis
+	for ln.end > ln.start && (st.measure(ln.start, ln.end)+st.measureSuffix(ln)).Round() > int(width) {
+		_, last := utf8.DecodeLastRuneInString(st.text[ln.start:ln.end])
+		ln.end = ln.start + len(trimRightSpace(st.text[ln.start:ln.end-last]))
+	}
+	return ln
+}
+
+func countSpaces(s string) int {
+	n := 0
+	for _, c := range s {
+		if unicode.IsSpace(c) {
+			n++
+		}
+	}
+	return n
+}
 
-	return comp.ToSprite(), nil
+func skipSpace(s string, i int) int {
+	return len(s) - len(strings.TrimLeftFunc(s[i:], unicode.IsSpace))
 }
 
 func defaultGenerator() *Generator {
 	return &Generator{
-		MinSize:    5,
-		MaxSize:    30,
-		Font:       render.DefaultFont(),
-		Text:       "placeholder",
-		Dimensions: floatgeom.Point2{50, 50},
+		MinSize:          5,
+		MaxSize:          30,
+		Font:             render.DefaultFont(),
+		Text:             "placeholder",
+		Dimensions:       floatgeom.Point2{50, 50},
+		Ellipsis:         "...",
+		LineHeight:       1,
+		ParagraphSpacing: 1,
 	}
 }
 
@@ -125,11 +398,30 @@ func New(options ...Option) (render.Modifiable, error) {
 	return gen.generate()
 }
 
+// NewLayout fits text as New does, but returns where the text was placed rather
+// than rendering it. The layout can be rendered with its Render method.
+func NewLayout(options ...Option) (Layout, error) {
+	gen := defaultGenerator()
+	for _, opt := range options {
+		opt(gen)
+	}
+	return gen.layout()
+}
+
 type Option (func(*Generator))
 
 func String(s string) Option {
 	return func(g *Generator) {
 		g.Text = s
+		g.TextPtr = nil
+	}
+}
+
+// StringPtr sets the text to the string behind s. A Live created with this
+// option refits whenever that string changes.
+func StringPtr(s *string) Option {
+	return func(g *Generator) {
+		g.TextPtr = s
 	}
 }
 
@@ -170,3 +462,122 @@ func WithBreakStyle(bs BreakStyle) Option {
 		g.BreakStyle = bs
 	}
 }
+
+// WithOverflow sets how text that does not fit at MinSize is handled.
+func WithOverflow(o Overflow) Option {
+	return func(g *Generator) {
+		g.Overflow = o
+	}
+}
+
+// Ellipsis sets the string OverflowEllipsis ends truncated text with.
+func Ellipsis(s string) Option {
+	return func(g *Generator) {
+		g.Ellipsis = s
+	}
+}
+
+// WithHorizontalAlign sets how each line is aligned within the dimensions.
+func WithHorizontalAlign(a HorizontalAlign) Option {
+	return func(g *Generator) {
+		g.HorizontalAlign = a
+	}
+}
+
+// WithVerticalAlign sets how the block of text is aligned within the dimensions.
+func WithVerticalAlign(a VerticalAlign) Option {
+	return func(g *Generator) {
+		g.VerticalAlign = a
+	}
+}
+
+// WithAlign sets both horizontal and vertical alignment.
+func WithAlign(h HorizontalAlign, v VerticalAlign) Option {
+	return func(g *Generator) {
+		g.HorizontalAlign = h
+		g.VerticalAlign = v
+	}
+}
+
+// WithHyphenator sets the Hyphenator used with BreakStyleHyphenate.
+func WithHyphenator(h Hyphenator) Option {
+	return func(g *Generator) {
+		g.Hyphenator = h
+	}
+}
+
+// WithMarkup enables parsing the text for inline markup, drawing it with the
+// fonts from family. The following tags are recognized, and must be closed
+// with a matching </tag>:
+//
+//	<b>: bold text
+//	<i>: italic text
+//	<color=#RRGGBBAA>, <color=#RRGGBB>, <color=name>: colored text, where name
+//	    is an SVG 1.1 color name
+//	<size=+N>, <size=-N>: text N points larger or smaller than the fitted size
+//	<name>: text in a style added with NamedStyle
+//
+// Unrecognized or unmatched tags are drawn as text.
+func WithMarkup(family FontFamily) Option {
+	return func(g *Generator) {
+		g.Markup = true
+		g.Family = family
+	}
+}
+
+// NamedStyle adds a style that marked up text can apply with <name></name>.
+func NamedStyle(name string, s Style) Option {
+	return func(g *Generator) {
+		if g.Styles == nil {
+			g.Styles = make(map[string]Style)
+		}
+		g.Styles[name] = s
+	}
+}
+
+// LineHeight sets the multiplier applied to the distance between lines.
+func LineHeight(mult float64) Option {
+	return func(g *Generator) {
+		g.LineHeight = mult
+	}
+}
+
+// ParagraphSpacing sets the space added between paragraphs, in multiples of
+// the font size. Paragraphs are separated by blank lines.
+func ParagraphSpacing(ems float64) Option {
+	return func(g *Generator) {
+		g.ParagraphSpacing = ems
+	}
+}
+
+// FirstLineIndent sets how far the first line of each paragraph is indented,
+// in multiples of the font size.
+func FirstLineIndent(ems float64) Option {
+	return func(g *Generator) {
+		g.FirstLineIndent = ems
+	}
+}
+
+// LetterSpacing sets the space added between characters, in multiples of the
+// font size.
+func LetterSpacing(ems float64) Option {
+	return func(g *Generator) {
+		g.LetterSpacing = ems
+	}
+}
+
+// WithShape fits text inside sh, sized to the dimensions less any inset, rather
+// than the full rectangle. Each line is narrowed to the part of sh it covers.
+func WithShape(sh shape.Shape) Option {
+	return func(g *Generator) {
+		g.Shape = sh
+	}
+}
+
+// WithLineSpan narrows each line of text to the span fn returns for it. It has
+// no effect if a shape is also set.
+func WithLineSpan(fn LineSpan) Option {
+	return func(g *Generator) {
+		g.LineSpan = fn
+	}
+}
@@ -1,12 +1,24 @@
 package textfit
 
 import (
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/oakmound/oak/v4/alg/floatgeom"
+	"github.com/oakmound/oak/v4/event"
 	"github.com/oakmound/oak/v4/render"
+	"github.com/oakmound/oak/v4/scene"
+	"github.com/oakmound/oak/v4/shape"
 )
 
+const legendText = "The final episode of \"The Legend of High School\" show has leaked two hours before its premiere. As a moderator of the official \"The Legend of High School\" forum, you need to <b>keep the fans from learning any details about the ending of the show</b>.You've read the books before they made it a show, so you're already familiar with the plot points that are going to happen: <b>Alexzandre will Confess to Jeremiah</b>, <b>Olivette will miss the Polar Dance and not be sad about it</b>, <b> Tim Runnings will Tie with Ran Jennings for first place in the Quinqometry exams </b>, and finally <b>Sam Sam will show up to Graduation, Walk and end the series with his Goodbye Speech</b>. As always, this is a family friendly forum. <b>Discussion of non-canon romantic pairings of characters on the show is not allowed</b>. <b>Profanity of -any- kind is also not allowed</b>.\n<b>Linking to the leaked episode is not allowed </b>. <b> Asking for links to the leaked episode is also not allowed </b>."
+
 func TestNew(t *testing.T) {
 	type testCase struct {
 		opts []Option
@@ -15,7 +27,7 @@ func TestNew(t *testing.T) {
 	tcs := []testCase{
 		{
 			opts: []Option{
-				String("The final episode of \"The Legend of High School\" show has leaked two hours before its premiere. As a moderator of the official \"The Legend of High School\" forum, you need to <b>keep the fans from learning any details about the ending of the show</b>.You've read the books before they made it a show, so you're already familiar with the plot points that are going to happen: <b>Alexzandre will Confess to Jeremiah</b>, <b>Olivette will miss the Polar Dance and not be sad about it</b>, <b> Tim Runnings will Tie with Ran Jennings for first place in the Quinqometry exams </b>, and finally <b>Sam Sam will show up to Graduation, Walk and end the series with his Goodbye Speech</b>. As always, this is a family friendly forum. <b>Discussion of non-canon romantic pairings of characters on the show is not allowed</b>. <b>Profanity of -any- kind is also not allowed</b>.\n<b>Linking to the leaked episode is not allowed </b>. <b> Asking for links to the leaked episode is also not allowed </b>."),
+				String(legendText),
 				MinSize(1),
 				MaxSize(22),
 				Font(render.DefaultFont()),
@@ -30,3 +42,675 @@ func TestNew(t *testing.T) {
 		}
 	}
 }
+
+func TestNew_Overflow(t *testing.T) {
+	long := "This body of text is much too long to fit within a tiny box at any reasonable size."
+	base := []Option{
+		String(long),
+		MinSize(10),
+		MaxSize(12),
+		Dimensions(floatgeom.Point2{60, 30}),
+		WithBreakStyle(BreakStyleWord),
+	}
+	if _, err := New(base...); err == nil {
+		t.Fatal("expected error with default overflow")
+	}
+	all, err := NewLayout(append(base, WithOverflow(OverflowScroll))...)
+	if err != nil {
+		t.Fatalf("overflow scroll: got error: %v", err)
+	}
+	// clipping keeps the lines that end within the height, and no more
+	fits := 0
+	for fits < len(all.Lines) && all.Lines[fits].Bounds.Max.Y() <= 30 {
+		fits++
+	}
+	if fits == 0 || fits == len(all.Lines) {
+		t.Fatalf("expected some but not all of %d lines to fit, got %d", len(all.Lines), fits)
+	}
+	clip, err := NewLayout(append(base, WithOverflow(OverflowClip))...)
+	if err != nil {
+		t.Fatalf("overflow clip: got error: %v", err)
+	}
+	if len(clip.Lines) != fits {
+		t.Fatalf("expected clip to keep %d lines, got %d", fits, len(clip.Lines))
+	}
+	for i, ln := range clip.Lines {
+		if ln.Text != all.Lines[i].Text || ln.Bounds.Max.Y() > 30 {
+			t.Fatalf("expected clipped line %d to be %q within the height, got %q at %v", i, all.Lines[i].Text, ln.Text, ln.Bounds)
+		}
+	}
+	ellipsis, err := NewLayout(append(base, WithOverflow(OverflowEllipsis))...)
+	if err != nil {
+		t.Fatalf("overflow ellipsis: got error: %v", err)
+	}
+	if len(ellipsis.Lines) != fits {
+		t.Fatalf("expected ellipsis to keep %d lines, got %d", fits, len(ellipsis.Lines))
+	}
+	for i, ln := range ellipsis.Lines[:fits-1] {
+		if ln.Text != all.Lines[i].Text {
+			t.Fatalf("expected line %d to be %q, got %q", i, all.Lines[i].Text, ln.Text)
+		}
+	}
+	last := ellipsis.Lines[fits-1]
+	if !strings.HasSuffix(last.Text, "...") || last.Bounds.Max.Y() > 30 || last.Bounds.Max.X() > 60 {
+		t.Fatalf("expected last line to end with an ellipsis within the box, got %q at %v", last.Text, last.Bounds)
+	}
+	r, err := New(append(base, WithOverflow(OverflowScroll))...)
+	if err != nil {
+		t.Fatalf("overflow scroll: got error: %v", err)
+	}
+	sc, ok := r.(*Scroll)
+	if !ok {
+		t.Fatalf("expected *Scroll, got %T", r)
+	}
+	if w, h := sc.GetDims(); w != 60 || h != 30 {
+		t.Fatalf("expected 60x30 scroll window, got %vx%v", w, h)
+	}
+	if sc.MaxOffset() == 0 {
+		t.Fatal("expected scrollable content")
+	}
+	sc.ScrollBy(1 << 20)
+	if sc.Offset() != sc.MaxOffset() {
+		t.Fatalf("expected scroll to clamp to %v, got %v", sc.MaxOffset(), sc.Offset())
+	}
+}
+
+func TestNew_Align(t *testing.T) {
+	leftmost := func(r render.Modifiable) int {
+		rgba := r.GetRGBA()
+		b := rgba.Bounds()
+		for x := b.Min.X; x < b.Max.X; x++ {
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				if _, _, _, a := rgba.At(x, y).RGBA(); a != 0 {
+					return x
+				}
+			}
+		}
+		return -1
+	}
+	base := []Option{
+		String("short"),
+		MinSize(10),
+		MaxSize(10),
+		Dimensions(floatgeom.Point2{200, 100}),
+	}
+	left := MustNew(base...)
+	center := MustNew(append(base, WithHorizontalAlign(AlignCenter))...)
+	right := MustNew(append(base, WithHorizontalAlign(AlignRight))...)
+	l, c, r := leftmost(left), leftmost(center), leftmost(right)
+	if !(l < c && c < r) {
+		t.Fatalf("expected left < center < right, got %v, %v, %v", l, c, r)
+	}
+	if _, h := MustNew(append(base, WithVerticalAlign(AlignBottom))...).GetDims(); h < 100 {
+		t.Fatalf("expected bottom aligned text to reach the bottom, got height %v", h)
+	}
+	justified := MustNew(
+		String("a few words that wrap across more than one line"),
+		MinSize(10),
+		MaxSize(10),
+		Dimensions(floatgeom.Point2{120, 100}),
+		WithBreakStyle(BreakStyleWord),
+		WithHorizontalAlign(AlignJustify),
+	)
+	if w, _ := justified.GetDims(); w < 115 {
+		t.Fatalf("expected justified text to span the width, got %v", w)
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	opts := []Option{
+		String(legendText),
+		MinSize(1),
+		MaxSize(22),
+		Font(render.DefaultFont()),
+		Dimensions(floatgeom.Point2{300, 300}),
+		WithBreakStyle(BreakStyleWord),
+	}
+	for i := 0; i < b.N; i++ {
+		if _, err := New(opts...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestLineBreaksAround(t *testing.T) {
+	type testCase struct {
+		text       string
+		fit        int
+		last, next int
+	}
+	tcs := []testCase{
+		{text: "hello world", fit: 5, last: 6, next: 11},
+		{text: "hello world", fit: 3, last: -1, next: 6},
+		// ideographs may be broken between without spaces
+		{text: "Hello 世界です。", fit: len("Hello 世界"), last: len("Hello 世界"), next: len("Hello 世界で")},
+		// but not before closing punctuation
+		{text: "世界。Привет мир", fit: len("世界"), last: len("世"), next: len("世界。")},
+		{text: "Привет мир", fit: len("Привет м"), last: len("Привет "), next: len("Привет мир")},
+	}
+	for _, tc := range tcs {
+		last, next := lineBreaksAround(tc.text, tc.fit)
+		if last != tc.last || next != tc.next {
+			t.Errorf("%q fit %v: expected (%v, %v), got (%v, %v)", tc.text, tc.fit, tc.last, tc.next, last, next)
+		}
+	}
+}
+
+func TestWrap_RuneSafe(t *testing.T) {
+	text := "Ünïcödé çhäräctérs éverywhere, naïve café Привет 世界です"
+	for _, bs := range []BreakStyle{BreakStyleCharacter, BreakStyleWord, BreakStyleHyphenate} {
+		g := defaultGenerator()
+		g.Text = text
+		g.BreakStyle = bs
+		st := g.sizeText(g.styledText(), 12)
+		lines, _ := g.wrapSpan(st, 40, -1, nil, 0)
+		if len(lines) < 2 {
+			t.Fatalf("break style %v: expected text to wrap, got %v lines", bs, len(lines))
+		}
+		for _, ln := range lines {
+			if sec := st.lineText(ln); !utf8.ValidString(sec) {
+				t.Fatalf("break style %v: line %q is not valid utf8", bs, sec)
+			}
+		}
+	}
+}
+
+func TestWrap_Hyphenate(t *testing.T) {
+	g := defaultGenerator()
+	g.Text = "some hyphenation here"
+	g.BreakStyle = BreakStyleHyphenate
+	g.Hyphenator = HyphenationDictionary{"hyphenation": "hy-phen-a-tion"}
+	st := g.sizeText(g.styledText(), 12)
+	width := float64(sizedFontFor(g.Font, 12).measure("some hyphen-").Round())
+	lines, _ := g.wrapSpan(st, width, -1, nil, 0)
+	if first := st.lineText(lines[0]); first != "some hyphen-" {
+		t.Fatalf("expected first line to be hyphenated, got %q", first)
+	}
+	if second := st.lineText(lines[1]); !strings.HasPrefix(second, "ation") {
+		t.Fatalf("expected second line to continue the word, got %q", second)
+	}
+}
+
+func TestHyphenationDictionary(t *testing.T) {
+	hd := HyphenationDictionary{"hyphenation": "hy-phen-a-tion"}
+	points := hd.Hyphenate("(Hyphenation),")
+	expected := []int{3, 7, 8}
+	if len(points) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, points)
+	}
+	for i := range points {
+		if points[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, points)
+		}
+	}
+}
+
+func TestParseMarkup(t *testing.T) {
+	styles := map[string]Style{"warn": {Color: color.RGBA{255, 0, 0, 255}}}
+	st := parseMarkup("a <b>bold <i>both</i></b> <color=#00FF0080>green</color> <size=+4>big</size> <warn>w</warn> <u>x</u> 1 < 2 </b>", styles)
+	if expected := "a bold both green big w <u>x</u> 1 < 2 </b>"; st.text != expected {
+		t.Fatalf("expected text %q, got %q", expected, st.text)
+	}
+	type expectedRun struct {
+		text  string
+		style Style
+	}
+	expected := []expectedRun{
+		{"a ", Style{}},
+		{"bold ", Style{Bold: true}},
+		{"both", Style{Bold: true, Italic: true}},
+		{" ", Style{}},
+		{"green", Style{Color: color.RGBA{0, 255, 0, 128}}},
+		{" ", Style{}},
+		{"big", Style{SizeDelta: 4}},
+		{" ", Style{}},
+		{"w", styles["warn"]},
+		{" <u>x</u> 1 < 2 </b>", Style{}},
+	}
+	if len(st.runs) != len(expected) {
+		t.Fatalf("expected %v runs, got %v", len(expected), len(st.runs))
+	}
+	start := 0
+	for i, r := range st.runs {
+		if txt := st.text[start:r.end]; txt != expected[i].text || r.style != expected[i].style {
+			t.Fatalf("run %v: expected %q %+v, got %q %+v", i, expected[i].text, expected[i].style, txt, r.style)
+		}
+		start = r.end
+	}
+}
+
+func TestNew_Markup(t *testing.T) {
+	r, err := New(
+		String(legendText+" <color=#FF0000FF><size=+2>red</size></color>"),
+		MinSize(1),
+		MaxSize(22),
+		Dimensions(floatgeom.Point2{300, 300}),
+		WithBreakStyle(BreakStyleWord),
+		WithMarkup(FontFamily{}),
+	)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if w, h := r.GetDims(); w > 300 || h > 300+5 {
+		t.Fatalf("expected text to fit in 300x300, got %vx%v", w, h)
+	}
+	rgba := r.GetRGBA()
+	b := rgba.Bounds()
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			if c := rgba.RGBAAt(x, y); c.R > 200 && c.G == 0 && c.B == 0 {
+				return
+			}
+		}
+	}
+	t.Fatal("expected some text to be drawn in red")
+}
+
+func TestWrap_Paragraphs(t *testing.T) {
+	g := defaultGenerator()
+	g.Text = "first line\r\nsecond line\n\n  new paragraph\n\n\nlast"
+	g.BreakStyle = BreakStyleWord
+	g.ParagraphSpacing = .5
+	g.FirstLineIndent = 2
+	g.LineHeight = 1.5
+	st := g.sizeText(g.styledText(), 10)
+	lines, ok := g.wrapSpan(st, 1000, -1, nil, 0)
+	if !ok {
+		t.Fatal("expected text to fit")
+	}
+	type expectedLine struct {
+		text   string
+		y      float64
+		indent float64
+	}
+	expected := []expectedLine{
+		{"first line", 0, 20},
+		{"second line", 15, 0},
+		{"new paragraph", 35, 20},
+		{"last", 55, 20},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %v lines, got %v", len(expected), len(lines))
+	}
+	for i, ln := range lines {
+		if txt := st.lineText(ln); txt != expected[i].text || ln.y != expected[i].y || ln.indent != expected[i].indent {
+			t.Fatalf("line %v: expected %+v, got %q y %v indent %v", i, expected[i], txt, ln.y, ln.indent)
+		}
+	}
+}
+
+func TestSizedText_LetterSpacing(t *testing.T) {
+	g := defaultGenerator()
+	g.Text = "abcd"
+	plain := g.sizeText(g.styledText(), 10)
+	g.LetterSpacing = .5
+	spaced := g.sizeText(g.styledText(), 10)
+	diff := (spaced.measure(0, 4) - plain.measure(0, 4)).Round()
+	if diff != 15 {
+		t.Fatalf("expected three 5 pixel gaps between four letters, got %v", diff)
+	}
+}
+
+func TestLive(t *testing.T) {
+	s := "short"
+	l := NewLive(
+		StringPtr(&s),
+		MinSize(10),
+		MaxSize(10),
+		Dimensions(floatgeom.Point2{200, 100}),
+		WithBreakStyle(BreakStyleWord),
+	)
+	l.SetPos(5, 6)
+	l.SetLayer(3)
+	w1, h1 := l.GetDims()
+	if err := l.Err(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+
+	s = "a much longer string which will need to wrap onto more lines"
+	w2, h2 := l.GetDims()
+	if w2 <= w1 || h2 <= h1 {
+		t.Fatalf("expected refit text to grow from %vx%v, got %vx%v", w1, h1, w2, h2)
+	}
+
+	l.SetDimensions(floatgeom.Point2{20, 10})
+	if err := l.Err(); err == nil {
+		t.Fatal("expected error fitting into tiny dimensions")
+	}
+	if w, h := l.GetDims(); w != w2 || h != h2 {
+		t.Fatalf("expected last fit text to be kept, got %vx%v", w, h)
+	}
+
+	l.SetText("ok")
+	l.SetDimensions(floatgeom.Point2{200, 100})
+	if err := l.Err(); err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if l.X() != 5 || l.Y() != 6 || l.GetLayer() != 3 {
+		t.Fatalf("expected position and layer to be kept, got %v,%v layer %v", l.X(), l.Y(), l.GetLayer())
+	}
+}
+
+func TestNewLayout(t *testing.T) {
+	opts := []Option{
+		String("click <b>here</b> to continue, or wait to be hyphenated"),
+		MinSize(5),
+		MaxSize(20),
+		Dimensions(floatgeom.Point2{120, 80}),
+		WithBreakStyle(BreakStyleHyphenate),
+		WithHyphenator(HyphenationDictionary{"hyphenated": "hy-phen-at-ed"}),
+		WithMarkup(FontFamily{}),
+	}
+	l, err := NewLayout(opts...)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if l.Size < 5 || l.Size > 20 {
+		t.Fatalf("expected size within bounds, got %v", l.Size)
+	}
+	if len(l.Lines) < 2 {
+		t.Fatalf("expected multiple lines, got %v", len(l.Lines))
+	}
+	for _, ln := range l.Lines {
+		if ln.Text[:ln.End-ln.Start] != l.Text[ln.Start:ln.End] {
+			t.Fatalf("line text %q does not match its range %q", ln.Text, l.Text[ln.Start:ln.End])
+		}
+		lastX := math.Inf(-1)
+		for _, gl := range ln.Glyphs {
+			if gl.Index >= 0 {
+				if r, _ := utf8.DecodeRuneInString(l.Text[gl.Index:]); r != gl.Rune {
+					t.Fatalf("glyph %q at index %v does not match text rune %q", gl.Rune, gl.Index, r)
+				}
+			}
+			if gl.Bounds.Min.X() < lastX {
+				t.Fatalf("expected glyphs to progress left to right in line %q", ln.Text)
+			}
+			lastX = gl.Bounds.Min.X()
+			if gl.Bounds.Max.Y() != ln.Baseline {
+				t.Fatalf("expected glyph to sit on baseline %v, got %v", ln.Baseline, gl.Bounds.Max.Y())
+			}
+		}
+	}
+
+	here := strings.Index(l.Text, "here")
+	for _, ln := range l.Lines {
+		for _, gl := range ln.Glyphs {
+			if gl.Index != here {
+				continue
+			}
+			if !gl.Style.Bold {
+				t.Fatal("expected marked up glyph to be bold")
+			}
+			found, ok := l.GlyphAt(gl.Bounds.Center())
+			if !ok || found.Index != here {
+				t.Fatalf("expected to hit glyph at %v, got %+v", here, found)
+			}
+		}
+	}
+
+	r := MustNew(opts...)
+	w1, h1 := r.GetDims()
+	w2, h2 := l.Render().GetDims()
+	if w1 != w2 || h1 != h2 {
+		t.Fatalf("expected New and Layout.Render to match, got %vx%v and %vx%v", w1, h1, w2, h2)
+	}
+}
+
+func TestNewLayout_Shape(t *testing.T) {
+	dims := floatgeom.Point2{200, 200}
+	l, err := NewLayout(String(legendText), Dimensions(dims), WithShape(shape.Circle), WithBreakStyle(BreakStyleWord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Lines) < 3 {
+		t.Fatalf("expected several lines, got %d", len(l.Lines))
+	}
+	span := ShapeSpan(shape.Circle, dims)
+	for _, ln := range l.Lines {
+		left, right := span(ln.Bounds.Min.Y(), ln.Bounds.Max.Y())
+		if ln.Bounds.Min.X() < left || ln.Bounds.Max.X() > right+1 {
+			t.Fatalf("line %q at %v extends outside circle span %v-%v", ln.Text, ln.Bounds, left, right)
+		}
+	}
+	first, middle := l.Lines[0], l.Lines[len(l.Lines)/2]
+	if first.Bounds.Min.X() <= middle.Bounds.Min.X() {
+		t.Fatalf("expected first line to start right of middle line: %v vs %v", first.Bounds, middle.Bounds)
+	}
+
+	// a span narrowing lines as they go down
+	l, err = NewLayout(String(legendText), Dimensions(dims), WithBreakStyle(BreakStyleWord),
+		WithLineSpan(func(top, bottom float64) (float64, float64) {
+			return 0, 200 - bottom/2
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ln := range l.Lines {
+		if ln.Bounds.Max.X() > 200-ln.Bounds.Max.Y()/2+1 {
+			t.Fatalf("line %q at %v extends outside span", ln.Text, ln.Bounds)
+		}
+	}
+
+	// an inset larger than the area leaves no room, rather than panicking
+	if _, err := NewLayout(String(legendText), Dimensions(floatgeom.Point2{10, 10}), Inset(8, 8), WithShape(shape.Circle)); err == nil {
+		t.Fatal("expected error fitting text to a shape with no room")
+	}
+	if _, err := NewLayout(String(legendText), Dimensions(floatgeom.Point2{10, 10}), Inset(8, 8)); err == nil {
+		t.Fatal("expected error fitting text to an area with no room")
+	}
+	if left, right := ShapeSpan(shape.Circle, floatgeom.Point2{-6, -6})(0, 10); left != 0 || right != 0 {
+		t.Fatalf("expected empty span for negative dimensions, got %v-%v", left, right)
+	}
+}
+
+func TestNewLayout_Bidi(t *testing.T) {
+	runes := func(ln Line) string {
+		var sb strings.Builder
+		for _, gl := range ln.Glyphs {
+			sb.WriteRune(gl.Rune)
+		}
+		return sb.String()
+	}
+	layout := func(text string, opts ...Option) Layout {
+		t.Helper()
+		opts = append([]Option{String(text), MinSize(12), MaxSize(12), Dimensions(floatgeom.Point2{300, 100})}, opts...)
+		l, err := NewLayout(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+
+	// right to left words are reversed within left to right lines, and left
+	// to right words are kept in order within right to left lines
+	for text, want := range map[string]string{
+		"abc אבג דה def": "abc הד גבא def",
+		"אבג (דה) def":   "def (הד) גבא",
+		"plain text":     "plain text",
+	} {
+		l := layout(text)
+		if len(l.Lines) != 1 {
+			t.Fatalf("expected %q on one line, got %d", text, len(l.Lines))
+		}
+		ln := l.Lines[0]
+		if got := runes(ln); got != want {
+			t.Fatalf("expected %q displayed as %q, got %q", text, want, got)
+		}
+		if ln.Text != text {
+			t.Fatalf("expected line text to stay in logical order, got %q", ln.Text)
+		}
+		lastX := math.Inf(-1)
+		for _, gl := range ln.Glyphs {
+			if gl.Bounds.Min.X() < lastX {
+				t.Fatalf("expected glyphs of %q to progress left to right", text)
+			}
+			lastX = gl.Bounds.Min.X()
+			if r, _ := utf8.DecodeRuneInString(l.Text[gl.Index:]); r != gl.Rune && !strings.ContainsRune("()", r) {
+				t.Fatalf("glyph %q at index %v does not match text rune %q", gl.Rune, gl.Index, r)
+			}
+		}
+	}
+
+	// each wrapped line is reordered on its own, so the first words of a
+	// right to left paragraph stay on its first line, at its right
+	l := layout("אבג abc דהו def זחט ghi", Dimensions(floatgeom.Point2{60, 100}), WithBreakStyle(BreakStyleWord), WithHorizontalAlign(AlignStart))
+	if len(l.Lines) < 2 {
+		t.Fatalf("expected wrapped lines, got %d", len(l.Lines))
+	}
+	for i, ln := range l.Lines {
+		for _, gl := range ln.Glyphs {
+			if gl.Index < ln.Start || gl.Index >= ln.End {
+				t.Fatalf("glyph %q at %v placed outside line %d at %v-%v", gl.Rune, gl.Index, i, ln.Start, ln.End)
+			}
+		}
+		if math.Abs(ln.Bounds.Max.X()-60) > 1 {
+			t.Fatalf("expected right to left line %q to start at the right, got %v", ln.Text, ln.Bounds)
+		}
+	}
+	if first := l.Lines[0].Glyphs[len(l.Lines[0].Glyphs)-1]; first.Index != 0 {
+		t.Fatalf("expected the paragraph to start at the right of its first line, got %q at %v", first.Rune, first.Index)
+	}
+
+	// start and end alignment follow the direction of each paragraph
+	l = layout("abc\nא abc", WithHorizontalAlign(AlignStart))
+	if ltr, rtl := l.Lines[0].Bounds, l.Lines[1].Bounds; ltr.Min.X() != 0 || math.Abs(rtl.Max.X()-300) > 1 {
+		t.Fatalf("expected start aligned lines at the left and right, got %v and %v", ltr, rtl)
+	}
+	l = layout("abc\nא abc", WithHorizontalAlign(AlignEnd))
+	if ltr, rtl := l.Lines[0].Bounds, l.Lines[1].Bounds; math.Abs(ltr.Max.X()-300) > 1 || rtl.Min.X() != 0 {
+		t.Fatalf("expected end aligned lines at the right and left, got %v and %v", ltr, rtl)
+	}
+
+	// ellipses end right to left lines on their left
+	l = layout("א abc def ghi jkl mno", Dimensions(floatgeom.Point2{60, 14}), WithBreakStyle(BreakStyleWord), WithOverflow(OverflowEllipsis))
+	last := l.Lines[len(l.Lines)-1]
+	if last.Glyphs[0].Index != -1 || last.Glyphs[len(last.Glyphs)-1].Index == -1 {
+		t.Fatalf("expected ellipsis at the left of %q, got %q", last.Text, runes(last))
+	}
+
+	// typewriters reveal text in reading order, whichever way it is displayed
+	cm := event.NewCallerMap()
+	tw := NewTypewriter(&scene.Context{CallerMap: cm, Handler: event.NewBus(cm)}, l)
+	for i := 1; i < len(tw.glyphs); i++ {
+		if prev, gl := tw.glyphs[i-1], tw.glyphs[i]; gl.Index >= 0 && (prev.Index < 0 || gl.Index < prev.Index) {
+			t.Fatalf("expected %q at %v to be revealed before %q at %v", gl.Rune, gl.Index, prev.Rune, prev.Index)
+		}
+	}
+}
+
+func TestTypewriter(t *testing.T) {
+	cm := event.NewCallerMap()
+	ctx := &scene.Context{CallerMap: cm, Handler: event.NewBus(cm)}
+	l, err := NewLayout(String("Hi, you."), Dimensions(floatgeom.Point2{200, 50}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := NewTypewriter(ctx, l, CharactersPerSecond(10), Pause(time.Second, "."))
+
+	var lock sync.Mutex
+	var chars []rune
+	done := 0
+	b1 := event.Bind(ctx, TypewriterCharacter, tw, func(_ *Typewriter, gl Glyph) event.Response {
+		lock.Lock()
+		chars = append(chars, gl.Rune)
+		lock.Unlock()
+		return 0
+	})
+	b2 := event.Bind(ctx, TypewriterDone, tw, func(*Typewriter, struct{}) event.Response {
+		lock.Lock()
+		done++
+		lock.Unlock()
+		return 0
+	})
+	<-b1.Bound
+	<-b2.Bound
+	shown := func() int {
+		n := 0
+		rgba := tw.shown.GetRGBA()
+		for i := 3; i < len(rgba.Pix); i += 4 {
+			if rgba.Pix[i] != 0 {
+				n++
+			}
+		}
+		return n
+	}
+
+	// the first character is revealed immediately, then one every 100ms,
+	// with an extra 120ms after the comma
+	tw.Advance(0)
+	partial := shown()
+	if partial == 0 {
+		t.Fatal("expected first character to be revealed")
+	}
+	tw.Advance(450 * time.Millisecond)
+	if tw.revealed != 4 {
+		t.Fatalf("expected 4 characters revealed, got %d", tw.revealed)
+	}
+	if shown() <= partial {
+		t.Fatal("expected more text to be shown")
+	}
+	tw.Skip()
+	if !tw.Done() {
+		t.Fatal("expected typewriter to be done after skipping")
+	}
+	tw.Advance(time.Second)
+
+	time.Sleep(50 * time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	// events are triggered concurrently, so may arrive in any order
+	sort.Slice(chars, func(i, j int) bool { return chars[i] < chars[j] })
+	if string(chars) != ",Hi" {
+		t.Fatalf("expected character events for %q, got %q", ",Hi", string(chars))
+	}
+	if done != 1 {
+		t.Fatalf("expected one done event, got %d", done)
+	}
+}
+
+func TestTypewriter_Empty(t *testing.T) {
+	for name, text := range map[string]string{"empty": "", "newlines": "\n\n"} {
+		t.Run(name, func(t *testing.T) {
+			cm := event.NewCallerMap()
+			ctx := &scene.Context{CallerMap: cm, Handler: event.NewBus(cm)}
+			l, err := NewLayout(String(text), Dimensions(floatgeom.Point2{200, 50}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tw := NewTypewriter(ctx, l)
+
+			var lock sync.Mutex
+			done := 0
+			b := event.Bind(ctx, TypewriterDone, tw, func(*Typewriter, struct{}) event.Response {
+				lock.Lock()
+				done++
+				lock.Unlock()
+				return 0
+			})
+			<-b.Bound
+			doneCount := func() int {
+				time.Sleep(50 * time.Millisecond)
+				lock.Lock()
+				defer lock.Unlock()
+				return done
+			}
+
+			if !tw.Done() {
+				t.Fatal("expected typewriter with no characters to be done")
+			}
+			tw.Advance(0)
+			tw.Advance(time.Second)
+			tw.Skip()
+			if n := doneCount(); n != 1 {
+				t.Fatalf("expected one done event, got %d", n)
+			}
+			// resetting starts the text over, so it is done again
+			tw.Reset(l)
+			tw.Skip()
+			tw.Advance(0)
+			if n := doneCount(); n != 2 {
+				t.Fatalf("expected a done event after reset, got %d total", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package textfit
+
+import (
+	"image"
+	"image/draw"
+	"sort"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/oakmound/oak/v4/event"
+	"github.com/oakmound/oak/v4/render"
+	"github.com/oakmound/oak/v4/scene"
+)
+
+var (
+	// TypewriterCharacter: Triggered on a Typewriter as it reveals each
+	// character, other than whitespace, of its text.
+	TypewriterCharacter = event.RegisterEvent[Glyph]()
+	// TypewriterDone: Triggered on a Typewriter once all of its text has been
+	// revealed, whether over time or by skipping. Text with no characters is
+	// done on the first advance or skip after it is set.
+	TypewriterDone = event.RegisterEvent[struct{}]()
+)
+
+var _ render.Renderable = &Typewriter{}
+
+// A Typewriter draws laid out text, revealing it one character at a time.
+type Typewriter struct {
+	event.CallerID
+	render.LayeredPoint
+
+	handler event.Handler
+
+	lock   sync.Mutex
+	full   *render.Sprite
+	shown  *render.Sprite
+	glyphs []revealGlyph
+	// revealed is how many glyphs are shown
+	revealed int
+	// wait is how long until the next glyph is revealed
+	wait time.Duration
+	// finished is set once TypewriterDone has been triggered for the text
+	finished bool
+
+	interval time.Duration
+	pauses   map[rune]time.Duration
+}
+
+// A revealGlyph is a glyph with the area of the rendered text that is shown
+// along with it.
+type revealGlyph struct {
+	Glyph
+	area image.Rectangle
+}
+
+func (tw *Typewriter) CID() event.CallerID {
+	return tw.CallerID.CID()
+}
+
+// A TypewriterOption changes how a Typewriter reveals its text.
+type TypewriterOption func(*Typewriter)
+
+// CharactersPerSecond sets how quickly characters are revealed.
+func CharactersPerSecond(cps float64) TypewriterOption {
+	return func(tw *Typewriter) {
+		tw.interval = time.Duration(float64(time.Second) / cps)
+	}
+}
+
+// Pause sets how long the Typewriter waits, in addition to its usual interval,
+// after revealing any of the given characters. A zero duration removes the
+// pause for those characters.
+func Pause(d time.Duration, chars string) TypewriterOption {
+	return func(tw *Typewriter) {
+		for _, c := range chars {
+			if d == 0 {
+				delete(tw.pauses, c)
+				continue
+			}
+			tw.pauses[c] = d
+		}
+	}
+}
+
+// NewTypewriter creates a Typewriter revealing the text of l, which starts with
+// nothing shown. By default it reveals 30 characters per second, pausing after
+// sentence ending punctuation and briefly after commas, colons and semicolons.
+func NewTypewriter(ctx *scene.Context, l Layout, opts ...TypewriterOption) *Typewriter {
+	tw := &Typewriter{
+		LayeredPoint: render.NewLayeredPoint(0, 0, 0),
+		handler:      ctx,
+		interval:     time.Second / 30,
+		pauses: map[rune]time.Duration{
+			'.': 300 * time.Millisecond,
+			'!': 300 * time.Millisecond,
+			'?': 300 * time.Millisecond,
+			',': 120 * time.Millisecond,
+			';': 120 * time.Millisecond,
+			':': 120 * time.Millisecond,
+		},
+	}
+	for _, opt := range opts {
+		opt(tw)
+	}
+	tw.CallerID = ctx.Register(tw)
+	tw.Reset(l)
+
+	event.Bind(ctx, event.Enter, tw, func(tw *Typewriter, ep event.EnterPayload) event.Response {
+		tw.Advance(ep.SinceLastFrame)
+		return 0
+	})
+	return tw
+}
+
+// Reset replaces the Typewriter's text with that of l, and starts revealing it
+// from the beginning.
+func (tw *Typewriter) Reset(l Layout) {
+	full := l.sprite()
+	w, h := full.GetDims()
+	var glyphs []revealGlyph
+	for i, ln := range l.Lines {
+		// each line is revealed down to where the next starts, so that
+		// descenders are shown
+		bottom := h
+		if i+1 < len(l.Lines) {
+			bottom = int(l.Lines[i+1].Bounds.Min.Y())
+		}
+		if baseline := int(ln.Baseline + 0.5); bottom < baseline {
+			bottom = baseline
+		}
+		top := int(ln.Bounds.Min.Y())
+		lineStart := len(glyphs)
+		for j, gl := range ln.Glyphs {
+			left, right := int(gl.Bounds.Min.X()+0.5), int(gl.Bounds.Max.X()+0.5)
+			if j == 0 {
+				left = 0
+			}
+			if j == len(ln.Glyphs)-1 {
+				right = w
+			}
+			glyphs = append(glyphs, revealGlyph{
+				Glyph: gl,
+				area:  image.Rect(left, top, right, bottom),
+			})
+		}
+		// glyphs are placed in display order, but revealed in reading order,
+		// with any added hyphen or ellipsis last
+		line := glyphs[lineStart:]
+		sort.SliceStable(line, func(a, b int) bool {
+			return line[a].Index >= 0 && (line[b].Index < 0 || line[a].Index < line[b].Index)
+		})
+	}
+
+	tw.lock.Lock()
+	tw.full = full
+	tw.shown = render.NewEmptySprite(0, 0, w, h)
+	tw.glyphs = glyphs
+	tw.revealed = 0
+	tw.wait = 0
+	tw.finished = false
+	tw.lock.Unlock()
+}
+
+// Advance moves the Typewriter forward by d, revealing any characters due in
+// that time. Typewriters advance on their own each frame; Advance is only
+// needed to move them faster.
+func (tw *Typewriter) Advance(d time.Duration) {
+	tw.lock.Lock()
+	if tw.finished {
+		tw.lock.Unlock()
+		return
+	}
+	tw.wait -= d
+	var revealed []Glyph
+	for tw.wait <= 0 && tw.revealed < len(tw.glyphs) {
+		gl := tw.reveal()
+		if !unicode.IsSpace(gl.Rune) {
+			revealed = append(revealed, gl)
+		}
+		tw.wait += tw.interval + tw.pauses[gl.Rune]
+	}
+	done := tw.revealed == len(tw.glyphs)
+	tw.finished = done
+	tw.lock.Unlock()
+
+	for _, gl := range revealed {
+		event.TriggerForCallerOn(tw.handler, tw.CallerID, TypewriterCharacter, gl)
+	}
+	if done {
+		event.TriggerForCallerOn(tw.handler, tw.CallerID, TypewriterDone, struct{}{})
+	}
+}
+
+// reveal shows the next glyph and returns it. It must be called with the
+// Typewriter's lock held.
+func (tw *Typewriter) reveal() Glyph {
+	rg := tw.glyphs[tw.revealed]
+	tw.revealed++
+	draw.Draw(tw.shown.GetRGBA(), rg.area, tw.full.GetRGBA(), rg.area.Min, draw.Src)
+	return rg.Glyph
+}
+
+// Skip reveals all of the Typewriter's text at once. No TypewriterCharacter
+// events are triggered for the skipped characters.
+func (tw *Typewriter) Skip() {
+	tw.lock.Lock()
+	if tw.finished {
+		tw.lock.Unlock()
+		return
+	}
+	for tw.revealed < len(tw.glyphs) {
+		tw.reveal()
+	}
+	tw.finished = true
+	tw.lock.Unlock()
+	event.TriggerForCallerOn(tw.handler, tw.CallerID, TypewriterDone, struct{}{})
+}
+
+// Done reports whether all of the Typewriter's text has been revealed.
+func (tw *Typewriter) Done() bool {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	return tw.revealed == len(tw.glyphs)
+}
+
+// Draw the revealed text
+func (tw *Typewriter) Draw(buff draw.Image, xOff, yOff float64) {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	tw.shown.Draw(buff, tw.X()+xOff, tw.Y()+yOff)
+}
+
+// GetDims returns the dimensions of the Typewriter's fully revealed text.
+func (tw *Typewriter) GetDims() (int, int) {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	return tw.shown.GetDims()
+}
@@ -0,0 +1,313 @@
+package textqueue
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/oakmound/oak/v4/alg/floatgeom"
+	"github.com/oakmound/oak/v4/event"
+	"github.com/oakmound/oak/v4/render"
+	"github.com/oakmound/oak/v4/render/mod"
+	"github.com/oakmound/oak/v4/scene"
+)
+
+var (
+	// FloatingTextPublish: Triggered to spawn floating text on a specific Floater
+	FloatingTextPublish = event.RegisterEvent[FloatingText]()
+)
+
+// A Category classifies floating text, e.g. as damage or healing, and
+// selects the Style it is drawn with.
+type Category int
+
+// Categories with default styles. Other values may be given styles with
+// WithStyle.
+const (
+	CategoryDamage Category = iota
+	CategoryHeal
+	CategoryCrit
+)
+
+// A Style controls how text of a given Category looks.
+type Style struct {
+	Color color.Color
+	// Pop is the scale text starts at when spawned, shrinking back to 1
+	// over the Floater's pop duration. Values <= 1 disable the pop.
+	Pop float64
+}
+
+// An Anchor is anything with a world position that floating text can follow,
+// such as an *entities.Entity. Text only stacks with other text on the same
+// anchor, compared with ==; text on anchors whose types are not comparable,
+// like slices, never stacks.
+type Anchor interface {
+	X() float64
+	Y() float64
+}
+
+// FloatingText describes a single piece of text to spawn on a Floater.
+type FloatingText struct {
+	Text     string
+	Category Category
+	// Pos is the world position text spawns at, or the offset from Anchor
+	// if an Anchor is provided.
+	Pos    floatgeom.Point2
+	Anchor Anchor
+}
+
+type floatItem struct {
+	base   render.Modifiable
+	pop    float64
+	anchor Anchor
+	origin floatgeom.Point2
+	stack  floatgeom.Point2
+	// spawned is the Floater's clock when the item was emitted
+	spawned time.Duration
+	faded   int
+}
+
+// A Floater is a renderable entity that spawns short-lived text, like damage
+// numbers, which rises from a world position or anchor and fades away. Its
+// text moves with the time between frames, so it pauses along with the scene.
+type Floater struct {
+	event.CallerID
+	render.LayeredPoint
+
+	lock  sync.Mutex
+	items []*floatItem
+	// clock is how far the Floater has advanced since it was created
+	clock time.Duration
+	font  *render.Font
+	fonts map[Category]*render.Font
+
+	styles      map[Category]Style
+	velocity    floatgeom.Point2
+	gravity     float64
+	duration    time.Duration
+	fadeTime    time.Duration
+	popTime     time.Duration
+	stackOffset floatgeom.Point2
+	stackWindow time.Duration
+}
+
+func (f *Floater) CID() event.CallerID {
+	return f.CallerID.CID()
+}
+
+// FloaterOption configures a Floater.
+type FloaterOption func(*Floater)
+
+// WithVelocity sets the initial velocity of spawned text, in pixels per second.
+func WithVelocity(v floatgeom.Point2) FloaterOption {
+	return func(f *Floater) {
+		f.velocity = v
+	}
+}
+
+// WithGravity sets the downward acceleration of spawned text, in pixels per
+// second squared.
+func WithGravity(g float64) FloaterOption {
+	return func(f *Floater) {
+		f.gravity = g
+	}
+}
+
+// WithDuration sets how long spawned text lives and how much of that time
+// is spent fading out.
+func WithDuration(total, fade time.Duration) FloaterOption {
+	return func(f *Floater) {
+		f.duration = total
+		f.fadeTime = fade
+	}
+}
+
+// WithPopTime sets how long spawned text takes to shrink from its style's
+// Pop scale to its normal size.
+func WithPopTime(d time.Duration) FloaterOption {
+	return func(f *Floater) {
+		f.popTime = d
+	}
+}
+
+// WithStyle sets the style used for a category.
+func WithStyle(c Category, s Style) FloaterOption {
+	return func(f *Floater) {
+		f.styles[c] = s
+	}
+}
+
+// WithStacking sets the offset applied to text spawned at the same place as
+// other text that spawned within window, so simultaneous texts don't overlap.
+func WithStacking(offset floatgeom.Point2, window time.Duration) FloaterOption {
+	return func(f *Floater) {
+		f.stackOffset = offset
+		f.stackWindow = window
+	}
+}
+
+// NewFloater creates a Floater drawing with the provided font on the given layer.
+func NewFloater(ctx *scene.Context, layer int, font *render.Font, opts ...FloaterOption) *Floater {
+	f := &Floater{
+		font: font,
+		styles: map[Category]Style{
+			CategoryDamage: {Color: color.RGBA{255, 60, 60, 255}},
+			CategoryHeal:   {Color: color.RGBA{80, 230, 80, 255}},
+			CategoryCrit:   {Color: color.RGBA{255, 210, 40, 255}, Pop: 1.6},
+		},
+		velocity:    floatgeom.Point2{0, -60},
+		duration:    1 * time.Second,
+		fadeTime:    400 * time.Millisecond,
+		popTime:     150 * time.Millisecond,
+		stackOffset: floatgeom.Point2{0, -14},
+		stackWindow: 250 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	f.CallerID = ctx.Register(f)
+	f.LayeredPoint = render.NewLayeredPoint(0, 0, layer)
+
+	f.fonts = make(map[Category]*render.Font, len(f.styles))
+	for c, s := range f.styles {
+		if s.Color == nil {
+			f.fonts[c] = font
+			continue
+		}
+		fnt, err := font.RegenerateWith(func(fg render.FontGenerator) render.FontGenerator {
+			fg.Color = image.NewUniform(s.Color)
+			return fg
+		})
+		if err != nil {
+			fnt = font
+		}
+		f.fonts[c] = fnt
+	}
+
+	event.Bind(ctx, FloatingTextPublish, f, func(f *Floater, ft FloatingText) event.Response {
+		f.Emit(ft)
+		return 0
+	})
+	event.Bind(ctx, event.Enter, f, func(f *Floater, ep event.EnterPayload) event.Response {
+		f.Advance(ep.SinceLastFrame)
+		return 0
+	})
+
+	return f
+}
+
+// Emit spawns floating text.
+func (f *Floater) Emit(ft FloatingText) {
+	font, ok := f.fonts[ft.Category]
+	if !ok {
+		font = f.font
+	}
+	item := &floatItem{
+		base:   newTextSprite(font, ft.Text),
+		pop:    f.styles[ft.Category].Pop,
+		anchor: ft.Anchor,
+		origin: ft.Pos,
+	}
+
+	f.lock.Lock()
+	item.spawned = f.clock
+	stacked := 0
+	for _, other := range f.items {
+		if item.spawned-other.spawned > f.stackWindow {
+			continue
+		}
+		if !sameAnchor(other.anchor, item.anchor) {
+			continue
+		}
+		if other.origin.Distance(item.origin) > f.font.Height() {
+			continue
+		}
+		stacked++
+	}
+	item.stack = f.stackOffset.MulConst(float64(stacked))
+	f.items = append(f.items, item)
+	f.lock.Unlock()
+}
+
+// sameAnchor reports whether a and b are the same anchor, without panicking on
+// anchors of types that cannot be compared.
+func sameAnchor(a, b Anchor) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if ta != nil && !ta.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// EmitAt spawns floating text at a world position.
+func (f *Floater) EmitAt(str string, c Category, pos floatgeom.Point2) {
+	f.Emit(FloatingText{Text: str, Category: c, Pos: pos})
+}
+
+// EmitOn spawns floating text that follows an anchor, offset from its position.
+func (f *Floater) EmitOn(str string, c Category, a Anchor, offset floatgeom.Point2) {
+	f.Emit(FloatingText{Text: str, Category: c, Pos: offset, Anchor: a})
+}
+
+// Advance moves the Floater's clock forward by d, aging, moving and fading
+// its text. The Floater advances by the time between frames on its own.
+func (f *Floater) Advance(d time.Duration) {
+	f.lock.Lock()
+	f.clock += d
+	f.lock.Unlock()
+}
+
+// Draw the floater's live text
+func (f *Floater) Draw(buff draw.Image, xOff, yOff float64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	live := f.items[:0]
+	for _, item := range f.items {
+		elapsed := f.clock - item.spawned
+		if elapsed >= f.duration {
+			continue
+		}
+		live = append(live, item)
+
+		if fadeStart := f.duration - f.fadeTime; elapsed > fadeStart {
+			target := int(255 * float64(elapsed-fadeStart) / float64(f.fadeTime))
+			if target > item.faded {
+				item.base.Filter(mod.Fade(target - item.faded))
+				item.faded = target
+			}
+		}
+
+		t := elapsed.Seconds()
+		pos := item.origin.Add(item.stack).Add(f.velocity.MulConst(t))
+		pos[1] += 0.5 * f.gravity * t * t
+		if item.anchor != nil {
+			pos = pos.Add(floatgeom.Point2{item.anchor.X(), item.anchor.Y()})
+		}
+
+		r := item.base
+		if item.pop > 1 && elapsed < f.popTime {
+			progress := float64(elapsed) / float64(f.popTime)
+			scale := item.pop - (item.pop-1)*progress
+			r = item.base.Copy().Modify(mod.Scale(scale, scale))
+		}
+		w, h := r.GetDims()
+		r.Draw(buff, xOff+f.X()+pos.X()-math.Round(float64(w)/2), yOff+f.Y()+pos.Y()-math.Round(float64(h)/2))
+	}
+	for i := len(live); i < len(f.items); i++ {
+		f.items[i] = nil
+	}
+	f.items = live
+}
+
+// GetDims needs to have some size so give it the minimal one.
+func (f *Floater) GetDims() (int, int) {
+	return 1, 1
+}
@@ -79,8 +79,7 @@ func New(ctx *scene.Context, registeredEvents []event.UnsafeEventID, pos floatge
 }
 
 func PrintBind(tq *TextQueue, str string) event.Response {
-	r := tq.font.NewText(str, 0, 0)
-	m := r.ToSprite().Modify(mod.HighlightOff(colornames.Black, 2, 1, 1))
+	m := newTextSprite(tq.font, str)
 	tq.queueLock.Lock()
 	tq.queue = append([]queueItem{{
 		mod:    m,
@@ -90,6 +89,13 @@ func PrintBind(tq *TextQueue, str string) event.Response {
 	return 0
 }
 
+// newTextSprite renders str in the given font with the dark outline
+// shared by all text displayed by this package.
+func newTextSprite(font *render.Font, str string) render.Modifiable {
+	r := font.NewText(str, 0, 0)
+	return r.ToSprite().Modify(mod.HighlightOff(colornames.Black, 2, 1, 1))
+}
+
 const DisplayTextEvent = "DisplayText"
 
 const yBuffer = 3
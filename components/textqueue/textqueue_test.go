@@ -0,0 +1,185 @@
+package textqueue_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/oakmound/grove/components/textqueue"
+	"github.com/oakmound/oak/v4/alg/floatgeom"
+	"github.com/oakmound/oak/v4/event"
+	"github.com/oakmound/oak/v4/render"
+	"github.com/oakmound/oak/v4/scene"
+)
+
+var (
+	red  = color.RGBA{255, 0, 0, 255}
+	blue = color.RGBA{0, 0, 255, 255}
+)
+
+// newFloater creates a Floater whose text stays still, drawing damage in red
+// and healing in blue.
+func newFloater(opts ...textqueue.FloaterOption) *textqueue.Floater {
+	cm := event.NewCallerMap()
+	ctx := &scene.Context{CallerMap: cm, Handler: event.NewBus(cm)}
+	opts = append([]textqueue.FloaterOption{
+		textqueue.WithVelocity(floatgeom.Point2{}),
+		textqueue.WithStyle(textqueue.CategoryDamage, textqueue.Style{Color: red}),
+		textqueue.WithStyle(textqueue.CategoryHeal, textqueue.Style{Color: blue}),
+	}, opts...)
+	return textqueue.NewFloater(ctx, 0, render.DefaultFont(), opts...)
+}
+
+func drawFloater(f *textqueue.Floater) *image.RGBA {
+	buff := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	f.Draw(buff, 0, 0)
+	return buff
+}
+
+// inkOf returns the bounds of the pixels in img that match keep.
+func inkOf(img *image.RGBA, keep func(color.RGBA) bool) image.Rectangle {
+	var ink image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if keep(img.RGBAAt(x, y)) {
+				ink = ink.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return ink
+}
+
+func isRed(c color.RGBA) bool  { return c.R > 128 && c.B == 0 }
+func isBlue(c color.RGBA) bool { return c.B > 128 && c.R == 0 }
+
+func maxAlpha(img *image.RGBA) uint8 {
+	var max uint8
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] > max {
+			max = img.Pix[i]
+		}
+	}
+	return max
+}
+
+func TestFloater_Stacking(t *testing.T) {
+	offset := floatgeom.Point2{0, -20}
+	pos := floatgeom.Point2{100, 100}
+
+	t.Run("InWindow", func(t *testing.T) {
+		f := newFloater(textqueue.WithStacking(offset, time.Second))
+		f.EmitAt("8", textqueue.CategoryDamage, pos)
+		f.EmitAt("8", textqueue.CategoryHeal, pos)
+		drawn := drawFloater(f)
+		first, second := inkOf(drawn, isRed), inkOf(drawn, isBlue)
+		if first.Empty() || second.Empty() {
+			t.Fatalf("expected both texts to draw, got %v and %v", first, second)
+		}
+		if got := second.Min.Sub(first.Min); got != image.Pt(0, -20) {
+			t.Fatalf("expected second text stacked by %v, got %v", offset, got)
+		}
+	})
+	t.Run("ElsewhereUnstacked", func(t *testing.T) {
+		f := newFloater(textqueue.WithStacking(offset, time.Second))
+		f.EmitAt("8", textqueue.CategoryDamage, pos)
+		f.EmitAt("8", textqueue.CategoryHeal, pos.Add(floatgeom.Point2{-60, 0}))
+		drawn := drawFloater(f)
+		if got := inkOf(drawn, isBlue).Min.Sub(inkOf(drawn, isRed).Min); got != image.Pt(-60, 0) {
+			t.Fatalf("expected distant text not to stack, got offset %v", got)
+		}
+	})
+	t.Run("Window", func(t *testing.T) {
+		window := 20 * time.Millisecond
+		f := newFloater(textqueue.WithStacking(offset, window))
+		f.EmitAt("8", textqueue.CategoryDamage, pos)
+		f.Advance(window)
+		f.EmitAt("8", textqueue.CategoryHeal, pos)
+		drawn := drawFloater(f)
+		if got := inkOf(drawn, isBlue).Min.Sub(inkOf(drawn, isRed).Min); got != image.Pt(0, -20) {
+			t.Fatalf("expected text at the end of the window to stack, got offset %v", got)
+		}
+
+		f = newFloater(textqueue.WithStacking(offset, window))
+		f.EmitAt("8", textqueue.CategoryDamage, pos)
+		f.Advance(window + time.Millisecond)
+		f.EmitAt("8", textqueue.CategoryHeal, pos)
+		drawn = drawFloater(f)
+		if got := inkOf(drawn, isBlue).Min.Sub(inkOf(drawn, isRed).Min); got != image.Pt(0, 0) {
+			t.Fatalf("expected later text not to stack, got offset %v", got)
+		}
+	})
+}
+
+func TestFloater_Expiry(t *testing.T) {
+	f := newFloater(textqueue.WithDuration(400*time.Millisecond, 200*time.Millisecond))
+	f.EmitAt("8", textqueue.CategoryDamage, floatgeom.Point2{100, 100})
+	if a := maxAlpha(drawFloater(f)); a != 255 {
+		t.Fatalf("expected opaque text before fading, got alpha %v", a)
+	}
+	f.Advance(200 * time.Millisecond)
+	if a := maxAlpha(drawFloater(f)); a != 255 {
+		t.Fatalf("expected opaque text until the fade starts, got alpha %v", a)
+	}
+	f.Advance(100 * time.Millisecond)
+	if a := maxAlpha(drawFloater(f)); a == 0 || a == 255 {
+		t.Fatalf("expected partially faded text, got alpha %v", a)
+	}
+	f.Advance(99 * time.Millisecond)
+	if a := maxAlpha(drawFloater(f)); a == 0 {
+		t.Fatal("expected text to be drawn until its duration ends")
+	}
+	f.Advance(time.Millisecond)
+	if a := maxAlpha(drawFloater(f)); a != 0 {
+		t.Fatalf("expected text to expire, got alpha %v", a)
+	}
+}
+
+func TestFloater_Movement(t *testing.T) {
+	f := newFloater(textqueue.WithVelocity(floatgeom.Point2{0, -60}), textqueue.WithGravity(0))
+	f.EmitAt("8", textqueue.CategoryDamage, floatgeom.Point2{100, 100})
+	before := inkOf(drawFloater(f), isRed)
+	f.Advance(500 * time.Millisecond)
+	after := inkOf(drawFloater(f), isRed)
+	if got := after.Min.Sub(before.Min); got != image.Pt(0, -30) {
+		t.Fatalf("expected text to rise 30 pixels in half a second, got %v", got)
+	}
+}
+
+type pointAnchor struct {
+	x, y float64
+}
+
+func (p *pointAnchor) X() float64 { return p.x }
+func (p *pointAnchor) Y() float64 { return p.y }
+
+// sliceAnchor is an anchor of a type that cannot be compared.
+type sliceAnchor []float64
+
+func (s sliceAnchor) X() float64 { return s[0] }
+func (s sliceAnchor) Y() float64 { return s[1] }
+
+func TestFloater_Anchor(t *testing.T) {
+	anchor := &pointAnchor{50, 50}
+	f := newFloater()
+	f.EmitOn("8", textqueue.CategoryDamage, anchor, floatgeom.Point2{10, 0})
+	before := inkOf(drawFloater(f), isRed)
+	anchor.x, anchor.y = 80, 60
+	after := inkOf(drawFloater(f), isRed)
+	if before.Empty() {
+		t.Fatal("expected anchored text to draw")
+	}
+	if got := after.Min.Sub(before.Min); got != image.Pt(30, 10) {
+		t.Fatalf("expected text to follow anchor by 30,10, got %v", got)
+	}
+
+	// text on anchors that cannot be compared draws, but does not stack
+	f = newFloater(textqueue.WithStacking(floatgeom.Point2{0, -20}, time.Second))
+	f.EmitOn("8", textqueue.CategoryDamage, sliceAnchor{100, 100}, floatgeom.Point2{})
+	f.EmitOn("8", textqueue.CategoryHeal, sliceAnchor{100, 100}, floatgeom.Point2{})
+	drawn := drawFloater(f)
+	if got := inkOf(drawn, isBlue).Min.Sub(inkOf(drawn, isRed).Min); got != image.Pt(0, 0) {
+		t.Fatalf("expected text on incomparable anchors not to stack, got offset %v", got)
+	}
+}